@@ -0,0 +1,161 @@
+// Command pspec runs Puppet specification (.pspec) files without the need for a Go test file.
+//
+// Usage:
+//
+//	pspec [flags] [path ...]
+//	pspec pn [file ...]
+//
+// Each path is either a .pspec file or a directory that is searched recursively for such files. The
+// current directory is used when no path is given. The exit status is 0 when all examples pass, 1
+// when one or more examples fail, and 2 when the files cannot be found or loaded.
+//
+// With -watch, the examples are run again each time a spec file, or a path that is referenced by the
+// settings of an example, changes. Only the examples declared in the affected spec files are run. The
+// exit status is 0 when watching is interrupted and 2 when the files can no longer be found.
+//
+// When JSON events are written to stdout, the failures and the summary of the run are written to
+// stderr so that stdout contains nothing but the events.
+//
+// The pn command prints the PN of the Puppet source in each given file, or in stdin, in the indented
+// form that can be used in a Parses_to expectation.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"time"
+
+	"github.com/lyraproj/puppet-spec/pspec"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == `pn` {
+		os.Exit(pnCommand(os.Args[2:]))
+	}
+
+	verbose := flag.Bool(`v`, false, `print the name of each passing, pending, and skipped example`)
+	junit := flag.String(`junit`, ``, "write a JUnit XML report to the given `file`")
+	filter := flag.String(`filter`, ``, "run only examples whose full name matches the regular `expression`")
+	lines := flag.String(`line`, ``, "run only the examples declared at the comma separated `file:line` entries")
+	tags := flag.String(`tags`, ``, "run only examples with the comma separated `tags`, excluding those prefixed with ~")
+	jsonEvents := flag.String(`json`, ``, "write newline delimited JSON events to the given `file` (- for stdout)")
+	cache := flag.String(`failure-cache`, ``, "record the failed examples in the given `file` (-only-failures defaults it to "+pspec.DefaultFailureCache+")")
+	onlyFailures := flag.Bool(`only-failures`, false, `run only the examples that failed in the previous run`)
+	update := flag.Bool(`update`, false, `rewrite failing Parses_to and Evaluates_to expectations with the actual values and update snapshots`)
+	dryRun := flag.Bool(`dry-run`, false, `list the selected examples without running them`)
+	failFast := flag.Bool(`fail-fast`, false, `stop the run when the first example fails`)
+	maxFailures := flag.Int(`max-failures`, 0, "stop the run when `n` examples have failed")
+	shuffle := flag.Bool(`shuffle`, false, `run the examples in random order and print the seed used`)
+	seed := flag.Int64(`seed`, 0, "run the examples in the random order determined by the given `seed`")
+	timeout := flag.Duration(`timeout`, 0, "fail examples that run longer than the given `duration` unless they declare a Timeout")
+	watch := flag.Bool(`watch`, false, `run the examples again when spec files or paths referenced by their settings change`)
+	parallel := flag.Int(`parallel`, 0, "run at most `n` examples concurrently")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [path ...]\n       %s pn [file ...]\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	roots := flag.Args()
+	if len(roots) == 0 {
+		roots = []string{`.`}
+	}
+
+	opts := make([]pspec.Option, 0)
+	if *timeout > 0 {
+		opts = append(opts, pspec.DefaultTimeout(*timeout))
+	}
+	if *cache != `` {
+		opts = append(opts, pspec.FailureCache(*cache))
+	}
+	if *onlyFailures {
+		opts = append(opts, pspec.OnlyFailures())
+	}
+	if *update {
+		opts = append(opts, pspec.Update())
+	}
+	if *dryRun {
+		opts = append(opts, pspec.DryRun())
+	}
+	if *failFast {
+		opts = append(opts, pspec.FailFast())
+	}
+	if *maxFailures > 0 {
+		opts = append(opts, pspec.MaxFailures(*maxFailures))
+	}
+	if *shuffle {
+		opts = append(opts, pspec.Shuffle())
+	}
+	// Any seed can be given, including zero, so the flag is used when it is set rather than when it is non-zero
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == `seed` {
+			opts = append(opts, pspec.Seed(*seed))
+		}
+	})
+	if *parallel > 0 {
+		opts = append(opts, pspec.Parallel(*parallel))
+	}
+	if *junit != `` {
+		opts = append(opts, pspec.JUnitReport(*junit))
+	}
+	if *jsonEvents != `` {
+		opts = append(opts, pspec.JSONReport(*jsonEvents))
+	}
+	if *filter != `` {
+		rx, err := regexp.Compile(*filter)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+		opts = append(opts, pspec.Filter(rx))
+	}
+	if *tags != `` {
+		opts = append(opts, pspec.TagSelection(*tags)...)
+	}
+	if *lines != `` {
+		fls, err := pspec.Lines(*lines)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+		opts = append(opts, fls...)
+	}
+	// Keep stdout free for the JSON events when they are written there
+	out := os.Stdout
+	if *jsonEvents == `-` || *jsonEvents == `` && os.Getenv(`PSPEC_JSON`) == `-` {
+		out = os.Stderr
+	}
+
+	if *watch {
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		watchErr := make(chan error, 1)
+		go func() { watchErr <- pspec.Watch(out, *verbose, time.Second, roots, opts...) }()
+		select {
+		case err := <-watchErr:
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(2)
+			}
+		case <-interrupted:
+		}
+		os.Exit(0)
+	}
+
+	testFiles, err := pspec.FindTestFiles(roots...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	tests, err := pspec.LoadTests(testFiles, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	if !pspec.NewRunner(out, *verbose, opts...).Run(tests) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/puppet-spec/pspec"
+)
+
+// pnCommand prints the PN of the Puppet source in each of the given files in the form that is expected by
+// Parses_to. The source is read from stdin when no file is given or when the file is "-". The returned
+// value is the exit status.
+func pnCommand(files []string) int {
+	if len(files) == 0 {
+		files = []string{`-`}
+	}
+	for _, file := range files {
+		var content []byte
+		var err error
+		if file == `-` {
+			content, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			content, err = ioutil.ReadFile(file)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 2
+		}
+		p, err := pspec.SourcePN(file, string(content))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		fmt.Println(pspec.PrettyPN(p))
+	}
+	return 0
+}
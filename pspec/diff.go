@@ -0,0 +1,211 @@
+package pspec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+const (
+	// maxDifferences is the maximum number of differences that are included in a failure message
+	maxDifferences = 20
+
+	// longString is the length at which strings are compared line by line
+	longString = 80
+)
+
+// valueDiff collects the differences between two values
+type valueDiff struct {
+	differences []string
+}
+
+// equalsMessage returns the failure message for an assertion that the given values are equal. The
+// message contains a structural diff when the values are collections or objects and a line diff
+// when they are long strings.
+func equalsMessage(expected interface{}, actual interface{}) string {
+	if es, ok := stringOf(expected); ok {
+		if as, ok := stringOf(actual); ok && isLongString(es, as) {
+			return "strings differ (- expected, + actual):\n" + lineDiff(es, as)
+		}
+	}
+	ev, eok := expected.(px.Value)
+	av, aok := actual.(px.Value)
+	if eok && aok && (isStructured(ev) || isStructured(av)) {
+		d := &valueDiff{make([]string, 0)}
+		d.diff(``, ev, av)
+		if len(d.differences) > 0 {
+			return fmt.Sprintf("expected %s, got %s\n%s", typeName(ev), typeName(av), d.String())
+		}
+	}
+	return fmt.Sprintf("expected %T '%v', got %T '%v'", expected, expected, actual, actual)
+}
+
+func stringOf(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case px.StringValue:
+		return v.String(), true
+	}
+	return ``, false
+}
+
+func isLongString(a, b string) bool {
+	return len(a) > longString || len(b) > longString || strings.ContainsRune(a, '\n') || strings.ContainsRune(b, '\n')
+}
+
+func isStructured(v px.Value) bool {
+	switch v.(type) {
+	case px.StringValue:
+		return false
+	case px.List, px.OrderedMap, px.PuppetObject:
+		return true
+	}
+	return false
+}
+
+func typeName(v px.Value) string {
+	return v.PType().Name()
+}
+
+func (d *valueDiff) add(path string, format string, args ...interface{}) {
+	if path == `` {
+		path = `(top)`
+	}
+	d.differences = append(d.differences, path+`: `+fmt.Sprintf(format, args...))
+}
+
+// diff records the differences between the expected and actual value found at the given path
+func (d *valueDiff) diff(path string, expected, actual px.Value) {
+	if px.Equals(expected, actual, nil) {
+		return
+	}
+	switch ev := expected.(type) {
+	case px.StringValue:
+		if as, ok := actual.(px.StringValue); ok && isLongString(ev.String(), as.String()) {
+			d.add(path, "strings differ (- expected, + actual):\n%s", lineDiff(ev.String(), as.String()))
+			return
+		}
+	case px.OrderedMap:
+		if av, ok := actual.(px.OrderedMap); ok {
+			d.diffHashes(path, ev, av)
+			return
+		}
+	case px.List:
+		if av, ok := actual.(px.List); ok && isStructured(actual) {
+			if _, ok := actual.(px.OrderedMap); !ok {
+				d.diffLists(path, ev, av)
+				return
+			}
+		}
+	case px.PuppetObject:
+		if av, ok := actual.(px.PuppetObject); ok && ev.PType().Equals(av.PType(), nil) {
+			d.diffHashes(path, ev.InitHash(), av.InitHash())
+			return
+		}
+	}
+	et := typeName(expected)
+	at := typeName(actual)
+	if et != at {
+		d.add(path, `type mismatch, expected %s %s, got %s %s`, et, inspect(expected), at, inspect(actual))
+	} else {
+		d.add(path, `expected %s, got %s`, inspect(expected), inspect(actual))
+	}
+}
+
+func (d *valueDiff) diffHashes(path string, expected, actual px.OrderedMap) {
+	expected.EachPair(func(k, ev px.Value) {
+		if av, ok := actual.Get(k); ok {
+			d.diff(path+`[`+inspect(k)+`]`, ev, av)
+		} else {
+			d.add(path, `missing key %s`, inspect(k))
+		}
+	})
+	actual.EachPair(func(k, _ px.Value) {
+		if _, ok := expected.Get(k); !ok {
+			d.add(path, `unexpected key %s`, inspect(k))
+		}
+	})
+}
+
+func (d *valueDiff) diffLists(path string, expected, actual px.List) {
+	el := expected.Len()
+	al := actual.Len()
+	n := el
+	if al < n {
+		n = al
+	}
+	for i := 0; i < n; i++ {
+		d.diff(fmt.Sprintf(`%s[%d]`, path, i), expected.At(i), actual.At(i))
+	}
+	for i := n; i < el; i++ {
+		d.add(fmt.Sprintf(`%s[%d]`, path, i), `missing element %s`, inspect(expected.At(i)))
+	}
+	for i := n; i < al; i++ {
+		d.add(fmt.Sprintf(`%s[%d]`, path, i), `unexpected element %s`, inspect(actual.At(i)))
+	}
+}
+
+func (d *valueDiff) String() string {
+	b := strings.Builder{}
+	for i, s := range d.differences {
+		if i == maxDifferences {
+			fmt.Fprintf(&b, "  ... and %d more differences\n", len(d.differences)-maxDifferences)
+			break
+		}
+		b.WriteString(`  `)
+		b.WriteString(strings.Replace(s, "\n", "\n    ", -1))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// inspect returns the Puppet representation of the given value
+func inspect(v px.Value) string {
+	return types.PuppetSprintf(`%p`, v)
+}
+
+// lineDiff returns a diff of the lines of the given strings. Lines that are only in a are prefixed
+// with "- ", lines that are only in b are prefixed with "+ ", and common lines are prefixed with two
+// spaces.
+func lineDiff(a, b string) string {
+	al := strings.Split(a, "\n")
+	bl := strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of al[i:] and bl[j:]
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	r := strings.Builder{}
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			r.WriteString(`  ` + al[i] + "\n")
+			i++
+			j++
+		case j < len(bl) && (i == len(al) || lcs[i][j+1] > lcs[i+1][j]):
+			r.WriteString(`+ ` + bl[j] + "\n")
+			j++
+		default:
+			r.WriteString(`- ` + al[i] + "\n")
+			i++
+		}
+	}
+	return strings.TrimSuffix(r.String(), "\n")
+}
@@ -0,0 +1,108 @@
+package pspec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// testHash returns a hash with the given keys and values, given in alternating order
+func testHash(keyValues ...interface{}) px.Value {
+	entries := make([]*types.HashEntry, 0, len(keyValues)/2)
+	for i := 0; i < len(keyValues); i += 2 {
+		entries = append(entries, types.WrapHashEntry2(keyValues[i].(string), keyValues[i+1].(px.Value)))
+	}
+	return types.WrapHash(entries)
+}
+
+// testArray returns an array with the given values
+func testArray(values ...px.Value) px.Value {
+	return types.WrapValues(values)
+}
+
+// differences returns the differences that a valueDiff finds between the given values
+func differences(expected, actual px.Value) []string {
+	d := &valueDiff{make([]string, 0)}
+	d.diff(``, expected, actual)
+	return d.differences
+}
+
+func TestValueDiff(t *testing.T) {
+	one := types.WrapInteger(1)
+	two := types.WrapInteger(2)
+	three := types.WrapInteger(3)
+	for _, tc := range []struct {
+		name     string
+		expected px.Value
+		actual   px.Value
+		diffs    []string
+	}{
+		{`path to first difference`,
+			testHash(`a`, testArray(one, testHash(`b`, two))),
+			testHash(`a`, testArray(one, testHash(`b`, three))),
+			[]string{`['a'][1]['b']: expected 2, got 3`}},
+		{`added and removed keys`,
+			testHash(`a`, one, `b`, two),
+			testHash(`a`, one, `c`, three),
+			[]string{`(top): missing key 'b'`, `(top): unexpected key 'c'`}},
+		{`added and removed elements`,
+			testArray(one, two),
+			testArray(one),
+			[]string{`[1]: missing element 2`}},
+		{`type mismatch at path`,
+			testHash(`a`, testArray(one)),
+			testHash(`a`, testArray(types.WrapString(`1`))),
+			[]string{`['a'][0]: type mismatch, expected Integer 1, got String '1'`}},
+		{`equal values`,
+			testHash(`a`, testArray(one)),
+			testHash(`a`, testArray(one)),
+			[]string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diffs := differences(tc.expected, tc.actual)
+			if strings.Join(diffs, "\n") != strings.Join(tc.diffs, "\n") {
+				t.Errorf("expected %q, got %q", tc.diffs, diffs)
+			}
+		})
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected string
+		actual   string
+		diff     string
+	}{
+		{`changed line`, "a\nb\nc", "a\nx\nc", "  a\n- b\n+ x\n  c"},
+		{`added line`, "a\nc", "a\nb\nc", "  a\n+ b\n  c"},
+		{`removed line`, "a\nb\nc", "a\nc", "  a\n- b\n  c"},
+		{`equal`, "a\nb", "a\nb", "  a\n  b"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := lineDiff(tc.expected, tc.actual); diff != tc.diff {
+				t.Errorf("expected\n%s\ngot\n%s", tc.diff, diff)
+			}
+		})
+	}
+}
+
+func TestEqualsMessageOfLongStrings(t *testing.T) {
+	long := strings.Repeat(`x`, longString)
+	msg := equalsMessage(types.WrapString(long+"\nsame\nold"), types.WrapString(long+"\nsame\nnew"))
+	expected := "strings differ (- expected, + actual):\n  " + long + "\n  same\n- old\n+ new"
+	if msg != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, msg)
+	}
+}
+
+func TestAssertEqualsUsesEqualsMessage(t *testing.T) {
+	a := &runnerAssertions{location: &testLocation{`test.pspec`, 3, 1}, failures: make([]string, 0)}
+	a.AssertEquals(testHash(`a`, types.WrapInteger(1)), testHash(`a`, types.WrapInteger(2)))
+	expected := "test.pspec:3: expected Hash, got Hash\n  ['a']: expected 1, got 2\n"
+	if len(a.failures) != 1 || a.failures[0] != expected {
+		t.Errorf("expected %q, got %q", expected, a.failures)
+	}
+}
@@ -17,38 +17,61 @@ type (
 )
 
 var pspecQRefs = map[string]string{
-	`Contain`:        `PSpec::Contain`,
-	`Directory`:      `PSpec::Directory`,
-	`Epp_source`:     `PSpec::Epp_source`,
-	`Error`:          `PSpec::Error`,
-	`Evaluates_ok`:   `PSpec::Evaluates_ok`,
-	`Evaluates_to`:   `PSpec::Evaluates_to`,
-	`Evaluates_with`: `PSpec::Evaluates_with`,
-	`Example`:        `PSpec::Example`,
-	`Examples`:       `PSpec::Examples`,
-	`Exclude`:        `PSpec::Exclude`,
-	`File`:           `PSpec::File`,
-	`Format`:         `PSpec::Format`,
-	`Get`:            `PSpec::Get`,
-	`Given`:          `PSpec::Given`,
-	`Include`:        `PSpec::Include`,
-	`Issue`:          `PSpec::Issue`,
-	`Let`:            `PSpec::Let`,
-	`Named_source`:   `PSpec::Named_source`,
-	`Notice`:         `PSpec::Notice`,
-	`Scope`:          `PSpec::Scope`,
-	`Settings`:       `PSpec::Settings`,
-	`Source`:         `PSpec::Source`,
-	`Match`:          `PSpec::Match`,
-	`Parser_options`: `PSpec::Parser_options`,
-	`Parses_to`:      `PSpec::Parses_to`,
-	`Validates_ok`:   `PSpec::Validates_ok`,
-	`Validates_with`: `PSpec::Validates_with`,
-	`Warning`:        `PSpec::Warning`,
-	`Unindent`:       `PSpec::Unindent`,
+	`After_all`:             `PSpec::After_all`,
+	`After_each`:            `PSpec::After_each`,
+	`Before_all`:            `PSpec::Before_all`,
+	`Before_each`:           `PSpec::Before_each`,
+	`Contain`:               `PSpec::Contain`,
+	`Directory`:             `PSpec::Directory`,
+	`Epp_source`:            `PSpec::Epp_source`,
+	`Error`:                 `PSpec::Error`,
+	`Evaluates_ok`:          `PSpec::Evaluates_ok`,
+	`Evaluates_to`:          `PSpec::Evaluates_to`,
+	`Evaluates_with`:        `PSpec::Evaluates_with`,
+	`Example`:               `PSpec::Example`,
+	`Example_table`:         `PSpec::Example_table`,
+	`Examples`:              `PSpec::Examples`,
+	`Exclude`:               `PSpec::Exclude`,
+	`Fexample`:              `PSpec::Fexample`,
+	`Fexamples`:             `PSpec::Fexamples`,
+	`File`:                  `PSpec::File`,
+	`Format`:                `PSpec::Format`,
+	`Get`:                   `PSpec::Get`,
+	`Given`:                 `PSpec::Given`,
+	`Include`:               `PSpec::Include`,
+	`Issue`:                 `PSpec::Issue`,
+	`It_behaves_like`:       `PSpec::It_behaves_like`,
+	`Let`:                   `PSpec::Let`,
+	`Named_source`:          `PSpec::Named_source`,
+	`Notice`:                `PSpec::Notice`,
+	`Scope`:                 `PSpec::Scope`,
+	`Serial`:                `PSpec::Serial`,
+	`Settings`:              `PSpec::Settings`,
+	`Shared_examples`:       `PSpec::Shared_examples`,
+	`Skip`:                  `PSpec::Skip`,
+	`Source`:                `PSpec::Source`,
+	`Tags`:                  `PSpec::Tags`,
+	`Timeout`:               `PSpec::Timeout`,
+	`Match`:                 `PSpec::Match`,
+	`Matches_snapshot`:      `PSpec::Matches_snapshot`,
+	`Parser_options`:        `PSpec::Parser_options`,
+	`Parses_like`:           `PSpec::Parses_like`,
+	`Parses_with_locations`: `PSpec::Parses_with_locations`,
+	`Parses_to`:             `PSpec::Parses_to`,
+	`Pending`:               `PSpec::Pending`,
+	`Validates_ok`:          `PSpec::Validates_ok`,
+	`Validates_with`:        `PSpec::Validates_with`,
+	`Warning`:               `PSpec::Warning`,
+	`Where`:                 `PSpec::Where`,
+	`Xexample`:              `PSpec::Xexample`,
+	`Xexamples`:             `PSpec::Xexamples`,
+	`Unindent`:              `PSpec::Unindent`,
 }
 
-const testNodes = `testNodes`
+const (
+	testNodes = `testNodes`
+	tableRows = `tableRows`
+)
 
 func NewSpecEvaluator(c pdsl.EvaluationContext) pdsl.Evaluator {
 	return &specEval{Evaluator: evaluator.NewEvaluator(c), path: make([]parser.Expression, 0)}
@@ -58,12 +81,28 @@ func (s *specEval) specError(issueCode issue.Code, semantic parser.Expression, a
 	return issue.NewReported(issueCode, issue.SeverityError, args, semantic)
 }
 
+// CreateTests evaluates the given expression and returns the tests that it declares. Shared examples used by
+// the tests are resolved when the tests are run, so they may be declared by expressions that are evaluated
+// later using the same context, just like the shared examples of the files loaded by LoadTests.
 func CreateTests(c pdsl.EvaluationContext, expression parser.Expression) []Test {
+	return createTests(CreateNodes(c, expression))
+}
+
+// CreateNodes evaluates the given expression and returns the nodes that it declares. Tests should
+// not be created from the nodes until all expressions that contribute shared examples have been
+// evaluated using the same context.
+func CreateNodes(c pdsl.EvaluationContext, expression parser.Expression) []Node {
 	c.Set(testNodes, make([]Node, 0))
+	if _, ok := c.Get(sharedExamples); !ok {
+		c.Set(sharedExamples, make(map[string]*Examples))
+	}
 	c.AddDefinitions(expression)
 	pdsl.TopEvaluate(c, expression)
 	ns, _ := c.Get(testNodes)
-	nodes := ns.([]Node)
+	return ns.([]Node)
+}
+
+func createTests(nodes []Node) []Test {
 	tests := make([]Test, len(nodes))
 	for i, node := range nodes {
 		tests[i] = node.CreateTest()
@@ -89,6 +128,12 @@ func addNode(c px.Context, n Node) {
 	c.Set(testNodes, append(nodes.([]Node), n))
 }
 
+// setTableRows passes the row expressions of the given Example_table call on to the constructor of the
+// table. The rows are evaluated into values that have no location.
+func setTableRows(c px.Context, call *parser.CallNamedFunctionExpression) {
+	c.Set(tableRows, tableRowExpressions(call))
+}
+
 func (s *specEval) evalBlockExpression(expr *parser.BlockExpression) px.Value {
 	stmts := expr.Statements()
 	result := px.Value(px.Undef)
@@ -129,6 +174,9 @@ func (s *specEval) evalQualifiedReference(qr *parser.QualifiedReference) px.Valu
 func (s *specEval) evalCallNamedFunctionExpression(call *parser.CallNamedFunctionExpression) px.Value {
 	if qr, ok := call.Functor().(*parser.QualifiedReference); ok {
 		if p, ok := pspecQRefs[qr.Name()]; ok {
+			if p == `PSpec::Example_table` {
+				setTableRows(s, call)
+			}
 			call = call.WithFunctor(qr.WithName(p))
 		}
 	}
@@ -2,9 +2,9 @@ package pspec
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lyraproj/issue/issue"
-	"github.com/lyraproj/pcore/pcore"
 	"github.com/lyraproj/pcore/px"
 	"github.com/lyraproj/pcore/types"
 	"github.com/lyraproj/puppet-evaluator/evaluator"
@@ -19,11 +19,33 @@ type (
 
 	Node interface {
 		Description() string
+
+		// Location returns the location of the call that declared the node
+		Location() issue.Location
+
+		// Tags returns the tags that were declared for the node. Tags declared by enclosing nodes are
+		// not included.
+		Tags() []string
+
+		// Focused returns true if the node was declared using Fexample or Fexamples
+		Focused() bool
+
+		skipReason() (string, bool)
+		pendingReason() (string, bool)
+		serial() bool
+		timeout() time.Duration
+		hooks(kind hookKind) []*Hook
+
 		Get(key string) (LazyValue, bool)
 		CreateTest() Test
 		collectInputs(context *TestContext, inputs []Input) []Input
 	}
 
+	// NodeOption is an argument to Example or Examples that modifies the created node
+	NodeOption interface {
+		applyTo(n *node)
+	}
+
 	Result interface {
 		CreateTest(actual interface{}) Executable
 
@@ -36,6 +58,14 @@ type (
 
 	node struct {
 		description string
+		location    issue.Location
+		tags        []string
+		skip        *Skip
+		pending     *Pending
+		focused     bool
+		serialized  bool
+		timeLimit   time.Duration
+		hookDefs    []*Hook
 		values      map[string]LazyValue
 		given       *Given
 	}
@@ -54,6 +84,27 @@ type (
 		inputs []Input
 	}
 
+	Tags struct {
+		tags []string
+	}
+
+	Skip struct {
+		reason string
+	}
+
+	Pending struct {
+		reason string
+	}
+
+	focusMarker struct{}
+
+	serialMarker struct{}
+
+	// Timeout is the maximum time that an example may run
+	Timeout struct {
+		duration time.Duration
+	}
+
 	ParseResult struct {
 		// ParseResult needs a location so that it can provide that to the PN parser
 		location issue.Location
@@ -61,7 +112,16 @@ type (
 		expected string
 	}
 
+	// ParseLikeResult is like ParseResult but the expected PN is a pattern that may contain wildcards
+	ParseLikeResult struct {
+		location issue.Location
+		example  *Example
+		expected string
+	}
+
 	EvaluationResult struct {
+		// EvaluationResult needs a location so that the expected value can be updated in the spec source
+		location issue.Location
 		example  *Example
 		expected px.Value
 	}
@@ -117,7 +177,11 @@ func (e *EvaluationResult) CreateTest(actual interface{}) Executable {
 		context.DoWithContext(func(c pdsl.EvaluationContext) {
 			actualResult, evalIssues := evaluate(c, actual)
 			failOnError(assertions, evalIssues)
-			assertions.AssertEquals(context.resolveLazyValue(e.expected), actualResult)
+			expected := context.resolveLazyValue(e.expected)
+			if !px.Equals(expected, actualResult, nil) && !isLazy(e.expected) && updateGolden(assertions, e.location, inspect(actualResult)) {
+				return
+			}
+			assertions.AssertEquals(expected, actualResult)
 		})
 	}
 }
@@ -126,27 +190,34 @@ func (e *EvaluationResult) setExample(example *Example) {
 	e.example = example
 }
 
-func (n *node) initialize(description string, given *Given) {
+func (n *node) initialize(description string, location issue.Location, given *Given) {
 	n.description = description
+	n.location = location
 	n.given = given
 	n.values = make(map[string]LazyValue, 8)
 }
 
+func (n *node) addOptions(options []NodeOption) {
+	for _, o := range options {
+		o.applyTo(n)
+	}
+}
+
 func (n *node) addLetDefs(lazyValueLets []*LazyValueLet) {
 	for _, ll := range lazyValueLets {
 		n.values[ll.valueName] = ll.value
 	}
 }
 
-func newExample(description string, given *Given, results []Result) *Example {
+func newExample(description string, location issue.Location, given *Given, results []Result) *Example {
 	e := &Example{results: results}
-	e.node.initialize(description, given)
+	e.node.initialize(description, location, given)
 	return e
 }
 
-func newExamples(description string, given *Given, children []Node) *Examples {
+func newExamples(description string, location issue.Location, given *Given, children []Node) *Examples {
 	e := &Examples{children: children}
-	e.node.initialize(description, given)
+	e.node.initialize(description, location, given)
 	return e
 }
 
@@ -182,13 +253,57 @@ func (e *Examples) CreateTest() Test {
 	for idx, child := range e.children {
 		tests[idx] = child.CreateTest()
 	}
-	return &TestGroup{testNode{e}, tests}
+	return &TestGroup{testNode: testNode{e}, tests: tests}
 }
 
 func (n *node) Description() string {
 	return n.description
 }
 
+func (n *node) Location() issue.Location {
+	return n.location
+}
+
+func (n *node) Tags() []string {
+	return n.tags
+}
+
+func (n *node) Focused() bool {
+	return n.focused
+}
+
+func (n *node) skipReason() (string, bool) {
+	if n.skip == nil {
+		return ``, false
+	}
+	return n.skip.reason, true
+}
+
+func (n *node) pendingReason() (string, bool) {
+	if n.pending == nil {
+		return ``, false
+	}
+	return n.pending.reason, true
+}
+
+func (n *node) serial() bool {
+	return n.serialized
+}
+
+func (n *node) timeout() time.Duration {
+	return n.timeLimit
+}
+
+func (n *node) hooks(kind hookKind) []*Hook {
+	hs := make([]*Hook, 0)
+	for _, h := range n.hookDefs {
+		if h.kind == kind {
+			hs = append(hs, h)
+		}
+	}
+	return hs
+}
+
 func (n *node) Get(key string) (v LazyValue, ok bool) {
 	v, ok = n.values[key]
 	return
@@ -196,7 +311,8 @@ func (n *node) Get(key string) (v LazyValue, ok bool) {
 
 func (p *ParseResult) CreateTest(actual interface{}) Executable {
 	path, source, epp := pathContentAndEpp(actual)
-	expectedPN := ParsePN(p.location, p.expected)
+	expected := parsePN(p.location, p.expected)
+	expectedPN := expected.toPN()
 
 	return func(context *TestContext, assertions Assertions) {
 		o := context.ParserOptions()
@@ -207,17 +323,13 @@ func (p *ParseResult) CreateTest(actual interface{}) Executable {
 		failOnError(assertions, issues)
 
 		// Automatically strip off blocks that contain one statement
-		if pr, ok := actual.(*parser.Program); ok {
-			actual = pr.Body()
-		}
-		if be, ok := actual.(*parser.BlockExpression); ok {
-			s := be.Statements()
-			if len(s) == 1 {
-				actual = s[0]
+		actualPN := stripBlock(actual).ToPN()
+		if expectedPN.String() != actualPN.String() {
+			if updateGolden(assertions, p.location, puppetString(actualPN.String())) {
+				return
 			}
+			assertions.Fail(pnDiffMessage(expected, pnNodeOf(actualPN)))
 		}
-		actualPN := actual.ToPN()
-		assertions.AssertEquals(expectedPN.String(), actualPN.String())
 	}
 }
 
@@ -225,6 +337,29 @@ func (p *ParseResult) setExample(example *Example) {
 	p.example = example
 }
 
+func (p *ParseLikeResult) CreateTest(actual interface{}) Executable {
+	path, source, epp := pathContentAndEpp(actual)
+	expected := parsePNPattern(p.location, p.expected)
+
+	return func(context *TestContext, assertions Assertions) {
+		o := context.ParserOptions()
+		if epp {
+			o = append(o, parser.EppMode)
+		}
+		actual, issues := parseAndValidate(path, context.resolveLazyValue(source).String(), false, o...)
+		failOnError(assertions, issues)
+
+		actualPN := pnNodeOf(stripBlock(actual).ToPN())
+		if !expected.matches(actualPN) {
+			assertions.Fail(pnDiffMessage(expected, actualPN))
+		}
+	}
+}
+
+func (p *ParseLikeResult) setExample(example *Example) {
+	p.example = example
+}
+
 func (s *SettingsInput) CreateTests(expected Result) []Executable {
 	// Settings input does not create any tests
 	return []Executable{func(tc *TestContext, assertions Assertions) {
@@ -233,7 +368,7 @@ func (s *SettingsInput) CreateTests(expected Result) []Executable {
 			panic(px.Error(ValueNotHash, issue.H{`type`: `Settings`}))
 		}
 		settings.EachPair(func(key, value px.Value) {
-			pcore.Set(key.String(), value)
+			tc.setSetting(key.String(), value)
 		})
 	}}
 }
@@ -264,6 +399,32 @@ func (ns *NamedSource) CreateTests(expected Result) []Executable {
 	return []Executable{expected.CreateTest(ns)}
 }
 
+func (t *Tags) applyTo(n *node) {
+	n.tags = append(n.tags, t.tags...)
+}
+
+func (s *Skip) applyTo(n *node) {
+	n.skip = s
+}
+
+func (p *Pending) applyTo(n *node) {
+	n.pending = p
+}
+
+var focus = &focusMarker{}
+
+func (f *focusMarker) applyTo(n *node) {
+	n.focused = true
+}
+
+func (s *serialMarker) applyTo(n *node) {
+	n.serialized = true
+}
+
+func (t *Timeout) applyTo(n *node) {
+	n.timeLimit = t.duration
+}
+
 func (ps *ParserOptions) CreateTests(expected Result) []Executable {
 	return []Executable{func(tc *TestContext, assertions Assertions) {
 		if tc.parserOptions == nil {
@@ -275,99 +436,70 @@ func (ps *ParserOptions) CreateTests(expected Result) []Executable {
 }
 
 func init() {
-	px.NewGoConstructor2(`PSpec::Example`,
-		func(l px.LocalTypes) {
-			l.Type2(`Given`, types.NewGoRuntimeType(&Given{}))
-			l.Type2(`Let`, types.NewGoRuntimeType(&LazyValueLet{}))
-			l.Type2(`SpecResult`, types.NewGoRuntimeType((*Result)(nil)))
-		},
+	newExampleConstructor(`PSpec::Example`, nil)
+	newExampleConstructor(`PSpec::Fexample`, focus)
+	newExampleConstructor(`PSpec::Xexample`, &Skip{`Temporarily skipped with Xexample`})
+	newExamplesConstructor(`PSpec::Examples`, nil)
+	newExamplesConstructor(`PSpec::Fexamples`, focus)
+	newExamplesConstructor(`PSpec::Xexamples`, &Skip{`Temporarily skipped with Xexamples`})
+
+	px.NewGoConstructor(`PSpec::Given`,
 		func(d px.Dispatch) {
-			d.Param(`String`)
-			d.RepeatedParam(`Variant[Let,Given,SpecResult]`)
+			d.RepeatedParam2(types.NewVariantType(types.DefaultStringType(), types.NewGoRuntimeType((*Input)(nil)), types.NewGoRuntimeType((*LazyValue)(nil))))
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				lets := make([]*LazyValueLet, 0)
-				var given *Given
-				results := make([]Result, 0)
-				for _, arg := range args[1:] {
-					if rt, ok := arg.(*types.RuntimeValue); ok {
-						i := rt.Interface()
-						switch i.(type) {
-						case *LazyValueLet:
-							lets = append(lets, i.(*LazyValueLet))
-						case *Given:
-							if given != nil {
+				return types.WrapRuntime(&Given{makeInputs(args)})
+			})
+		})
 
-							}
-							given = i.(*Given)
-						case Result:
-							results = append(results, i.(Result))
-						}
-					}
-				}
-				example := newExample(args[0].String(), given, results)
-				example.addLetDefs(lets)
-				for _, result := range results {
-					result.setExample(example)
+	px.NewGoConstructor(`PSpec::Tags`,
+		func(d px.Dispatch) {
+			d.RepeatedParam(`String[1]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				tags := make([]string, len(args))
+				for i, arg := range args {
+					tags[i] = arg.String()
 				}
-				return types.WrapRuntime(example)
+				return types.WrapRuntime(&Tags{tags})
 			})
 		})
 
-	px.NewGoConstructor2(`PSpec::Examples`,
-		func(l px.LocalTypes) {
-			l.Type2(`Given`, types.NewGoRuntimeType(&Given{}))
-			l.Type2(`Let`, types.NewGoRuntimeType(&LazyValueLet{}))
-			l.Type2(`ExampleNode`, types.NewGoRuntimeType((*Node)(nil)))
-			l.Type(`Nodes`, `Variant[ExampleNode, Array[Nodes]]`)
-		},
+	px.NewGoConstructor(`PSpec::Skip`,
 		func(d px.Dispatch) {
-			d.Param(`String`)
-			d.RepeatedParam(`Variant[Nodes,Let,Given]`)
+			d.OptionalParam(`String`)
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				lets := make([]*LazyValueLet, 0)
-				var given *Given
-				others := make([]px.Value, 0)
-				for _, arg := range args[1:] {
-					if rt, ok := arg.(*types.RuntimeValue); ok {
-						if l, ok := rt.Interface().(*LazyValueLet); ok {
-							lets = append(lets, l)
-							continue
-						}
-						if g, ok := rt.Interface().(*Given); ok {
-							given = g
-							continue
-						}
-					}
-					others = append(others, arg)
+				reason := `No reason given`
+				if len(args) > 0 {
+					reason = args[0].String()
 				}
-				ex := newExamples(args[0].String(), given, splatNodes(types.WrapValues(others)))
-				ex.addLetDefs(lets)
-				return types.WrapRuntime(ex)
+				return types.WrapRuntime(&Skip{reason})
 			})
 		})
 
-	px.NewGoConstructor(`PSpec::Given`,
+	px.NewGoConstructor(`PSpec::Pending`,
 		func(d px.Dispatch) {
-			d.RepeatedParam2(types.NewVariantType(types.DefaultStringType(), types.NewGoRuntimeType((*Input)(nil)), types.NewGoRuntimeType((*LazyValue)(nil))))
+			d.OptionalParam(`String`)
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				argc := len(args)
-				inputs := make([]Input, argc)
-				for idx := 0; idx < argc; idx++ {
-					arg := args[idx]
-					switch arg.(type) {
-					case px.StringValue:
-						inputs[idx] = &Source{[]*source{{arg, false}}}
-					default:
-						v := arg.(*types.RuntimeValue).Interface()
-						switch v.(type) {
-						case Input:
-							inputs[idx] = v.(Input)
-						default:
-							inputs[idx] = &Source{[]*source{{arg, false}}}
-						}
-					}
+				reason := `No reason given`
+				if len(args) > 0 {
+					reason = args[0].String()
 				}
-				return types.WrapRuntime(&Given{inputs})
+				return types.WrapRuntime(&Pending{reason})
+			})
+		})
+
+	px.NewGoConstructor(`PSpec::Serial`,
+		func(d px.Dispatch) {
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return types.WrapRuntime(&serialMarker{})
+			})
+		})
+
+	px.NewGoConstructor(`PSpec::Timeout`,
+		func(d px.Dispatch) {
+			d.Param(`Numeric[0]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				seconds := args[0].(px.Number).Float()
+				return types.WrapRuntime(&Timeout{time.Duration(seconds * float64(time.Second))})
 			})
 		})
 
@@ -439,6 +571,138 @@ func init() {
 		})
 }
 
+// newExampleConstructor creates the constructor for an Example node with the given name. The optional
+// marker is added to the options of each created node.
+func newExampleConstructor(name string, marker NodeOption) {
+	px.NewGoConstructor2(name,
+		func(l px.LocalTypes) {
+			l.Type2(`Given`, types.NewGoRuntimeType(&Given{}))
+			l.Type2(`Let`, types.NewGoRuntimeType(&LazyValueLet{}))
+			l.Type2(`SpecResult`, types.NewGoRuntimeType((*Result)(nil)))
+			l.Type2(`NodeOption`, types.NewGoRuntimeType((*NodeOption)(nil)))
+		},
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.RepeatedParam(`Variant[Let,Given,SpecResult,NodeOption]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				lets := make([]*LazyValueLet, 0)
+				var given *Given
+				results := make([]Result, 0)
+				options := make([]NodeOption, 0)
+				for _, arg := range args[1:] {
+					if rt, ok := arg.(*types.RuntimeValue); ok {
+						i := rt.Interface()
+						switch i.(type) {
+						case *LazyValueLet:
+							lets = append(lets, i.(*LazyValueLet))
+						case *Given:
+							if given != nil {
+
+							}
+							given = i.(*Given)
+						case Result:
+							results = append(results, i.(Result))
+						case NodeOption:
+							options = append(options, i.(NodeOption))
+						}
+					}
+				}
+				if marker != nil {
+					options = append(options, marker)
+				}
+				example := newExample(args[0].String(), c.StackTop(), given, results)
+				example.addOptions(options)
+				example.addLetDefs(lets)
+				for _, result := range results {
+					result.setExample(example)
+				}
+				return types.WrapRuntime(example)
+			})
+		})
+}
+
+// newExamplesConstructor creates the constructor for an Examples node with the given name. The optional
+// marker is added to the options of each created node.
+func newExamplesConstructor(name string, marker NodeOption) {
+	px.NewGoConstructor2(name,
+		func(l px.LocalTypes) {
+			l.Type2(`Given`, types.NewGoRuntimeType(&Given{}))
+			l.Type2(`Let`, types.NewGoRuntimeType(&LazyValueLet{}))
+			l.Type2(`ExampleNode`, types.NewGoRuntimeType((*Node)(nil)))
+			l.Type(`Nodes`, `Variant[ExampleNode, Array[Nodes]]`)
+			l.Type2(`NodeOption`, types.NewGoRuntimeType((*NodeOption)(nil)))
+			l.Type2(`Hook`, types.NewGoRuntimeType(&Hook{}))
+		},
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.RepeatedParam(`Variant[Nodes,Let,Given,NodeOption,Hook]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return types.WrapRuntime(newExamplesFromArgs(c, args, marker))
+			})
+		})
+}
+
+// newExamplesFromArgs creates an Examples node from the arguments given to an Examples constructor
+func newExamplesFromArgs(c px.Context, args []px.Value, marker NodeOption) *Examples {
+	lets := make([]*LazyValueLet, 0)
+	var given *Given
+	options := make([]NodeOption, 0)
+	hooks := make([]*Hook, 0)
+	others := make([]px.Value, 0)
+	for _, arg := range args[1:] {
+		if rt, ok := arg.(*types.RuntimeValue); ok {
+			if l, ok := rt.Interface().(*LazyValueLet); ok {
+				lets = append(lets, l)
+				continue
+			}
+			if g, ok := rt.Interface().(*Given); ok {
+				given = g
+				continue
+			}
+			if o, ok := rt.Interface().(NodeOption); ok {
+				options = append(options, o)
+				continue
+			}
+			if h, ok := rt.Interface().(*Hook); ok {
+				hooks = append(hooks, h)
+				continue
+			}
+		}
+		others = append(others, arg)
+	}
+	if marker != nil {
+		options = append(options, marker)
+	}
+	ex := newExamples(args[0].String(), c.StackTop(), given, splatNodes(types.WrapValues(others)))
+	ex.addOptions(options)
+	ex.hookDefs = hooks
+	ex.addLetDefs(lets)
+	return ex
+}
+
+// makeInputs creates an Input from each of the given arguments. Strings and lazy values are
+// considered to be sources.
+func makeInputs(args []px.Value) []Input {
+	argc := len(args)
+	inputs := make([]Input, argc)
+	for idx := 0; idx < argc; idx++ {
+		arg := args[idx]
+		switch arg.(type) {
+		case px.StringValue:
+			inputs[idx] = &Source{[]*source{{arg, false}}}
+		default:
+			v := arg.(*types.RuntimeValue).Interface()
+			switch v.(type) {
+			case Input:
+				inputs[idx] = v.(Input)
+			default:
+				inputs[idx] = &Source{[]*source{{arg, false}}}
+			}
+		}
+	}
+	return inputs
+}
+
 func splatNodes(args px.List) []Node {
 	nodes := make([]Node, 0)
 	args.Each(func(arg px.Value) {
@@ -2,6 +2,7 @@ package pspec
 
 import (
 	"bytes"
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -40,8 +41,18 @@ type (
 	}
 
 	IssueMatch struct {
-		issue   issue.Issue
-		argsMap *hash.StringHash
+		issue    issue.Issue
+		argsMap  *hash.StringHash
+		location *issueLocation
+	}
+
+	// issueLocation is the expected location of an issue. A zero line or pos and an empty file are not
+	// checked. A marker is resolved into a line and pos using the markers of the source.
+	issueLocation struct {
+		file   string
+		line   int
+		pos    int
+		marker string
 	}
 
 	StringMatch struct {
@@ -90,6 +101,61 @@ func (e *Expectation) MatchEntries(b *bytes.Buffer, log *px.ArrayLogger, allIssu
 	}
 }
 
+// withMarkers returns a copy of this expectation where all issue matches that refer to a marker are
+// replaced by matches for the location of that marker
+func (e *Expectation) withMarkers(assertions Assertions, markers map[string]*sourceMarker) *Expectation {
+	les := make([]*LevelExpectation, len(e.levelExpectations))
+	for i, le := range e.levelExpectations {
+		includes := make([]*Include, len(le.includes))
+		for j, in := range le.includes {
+			includes[j] = &Include{matchersWithMarkers(assertions, in.matchers, markers)}
+		}
+		excludes := make([]*Exclude, len(le.excludes))
+		for j, ex := range le.excludes {
+			excludes[j] = &Exclude{matchersWithMarkers(assertions, ex.matchers, markers)}
+		}
+		les[i] = &LevelExpectation{le.level, includes, excludes}
+	}
+	return &Expectation{les}
+}
+
+// usesMarkers returns true if an issue match of this expectation refers to a marker
+func (e *Expectation) usesMarkers() bool {
+	for _, le := range e.levelExpectations {
+		for _, in := range le.includes {
+			if matchersUseMarkers(in.matchers) {
+				return true
+			}
+		}
+		for _, ex := range le.excludes {
+			if matchersUseMarkers(ex.matchers) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchersUseMarkers(matchers []Match) bool {
+	for _, m := range matchers {
+		if im, ok := m.(*IssueMatch); ok && im.location != nil && im.location.marker != `` {
+			return true
+		}
+	}
+	return false
+}
+
+func matchersWithMarkers(assertions Assertions, matchers []Match, markers map[string]*sourceMarker) []Match {
+	result := make([]Match, len(matchers))
+	for i, m := range matchers {
+		if im, ok := m.(*IssueMatch); ok {
+			m = im.withMarkers(assertions, markers)
+		}
+		result[i] = m
+	}
+	return result
+}
+
 func issuesForLevel(issues []issue.Reported, level px.LogLevel) []issue.Reported {
 	levelIssues := make([]issue.Reported, 0)
 	severity := level.Severity()
@@ -209,6 +275,9 @@ func (im *IssueMatch) MatchIssue(issue issue.Reported) bool {
 	if im.issue.Code() != issue.Code() {
 		return false
 	}
+	if im.location != nil && !im.location.matches(issue.Location()) {
+		return false
+	}
 	if im.argsMap == nil {
 		return true
 	}
@@ -244,9 +313,70 @@ func (im *IssueMatch) MatchIssue(issue issue.Reported) bool {
 }
 
 func (im *IssueMatch) String() string {
+	if im.location != nil {
+		return string(im.issue.Code()) + ` at ` + im.location.String()
+	}
 	return string(im.issue.Code())
 }
 
+// withMarkers returns a copy of this match where the marker of the location is replaced by the line
+// and pos of the marker with that label. The match itself is returned when it has no marker.
+func (im *IssueMatch) withMarkers(assertions Assertions, markers map[string]*sourceMarker) *IssueMatch {
+	if im.location == nil || im.location.marker == `` {
+		return im
+	}
+	m, ok := markers[im.location.marker]
+	if !ok {
+		assertions.Fail(fmt.Sprintf(`%s: no marker labeled '%s'`, im.issue.Code(), im.location.marker))
+		return im
+	}
+	return &IssueMatch{im.issue, im.argsMap, &issueLocation{file: im.location.file, line: m.line, pos: m.pos}}
+}
+
+// makeIssueLocation creates the location of an issue from a hash of the issueLocationType
+func makeIssueLocation(h *types.Hash) *issueLocation {
+	l := &issueLocation{}
+	h.EachPair(func(k, v px.Value) {
+		switch k.String() {
+		case `file`:
+			l.file = v.String()
+		case `line`:
+			l.line = int(v.(px.Number).Int())
+		case `pos`:
+			l.pos = int(v.(px.Number).Int())
+		case `marker`:
+			l.marker = v.String()
+		}
+	})
+	return l
+}
+
+func (l *issueLocation) matches(loc issue.Location) bool {
+	if loc == nil {
+		return false
+	}
+	return (l.file == `` || l.file == loc.File()) &&
+		(l.line == 0 || l.line == loc.Line()) &&
+		(l.pos == 0 || l.pos == loc.Pos())
+}
+
+func (l *issueLocation) String() string {
+	parts := make([]string, 0, 4)
+	if l.file != `` {
+		parts = append(parts, `file `+l.file)
+	}
+	if l.marker != `` {
+		parts = append(parts, `marker '`+l.marker+`'`)
+	}
+	if l.line != 0 {
+		parts = append(parts, fmt.Sprintf(`line %d`, l.line))
+	}
+	if l.pos != 0 {
+		parts = append(parts, fmt.Sprintf(`pos %d`, l.pos))
+	}
+	return strings.Join(parts, `, `)
+}
+
 func (rm *RegexpMatch) MatchString(str string) bool {
 	return rm.regexp.MatchString(str)
 }
@@ -291,6 +421,13 @@ var matchArgType = types.NewVariantType(types.DefaultStringType(), types.Default
 var matchersType = types.NewVariantType(types.DefaultStringType(), types.DefaultRegexpType(), issueType, matchType)
 var expectationsType = types.NewVariantType(types.DefaultStringType(), types.DefaultRegexpType(), issueType, matchType, includeType, excludeType)
 
+const issueLocationType = `Struct[
+  Optional['file'] => String[1],
+  Optional['line'] => Integer[1],
+  Optional['pos'] => Integer[1],
+  Optional['marker'] => String[1]
+]`
+
 func makeMatches(name string, args []px.Value) (result []Match) {
 	result = make([]Match, len(args))
 	for ix, arg := range args {
@@ -305,7 +442,7 @@ func makeMatches(name string, args []px.Value) (result []Match) {
 			x := arg.Interface()
 			switch x.(type) {
 			case issue.Issue:
-				result[ix] = &IssueMatch{x.(issue.Issue), nil}
+				result[ix] = &IssueMatch{x.(issue.Issue), nil, nil}
 				continue
 			case Match:
 				result[ix] = x.(Match)
@@ -342,7 +479,7 @@ func makeExpectations(name string, level px.LogLevel, args []px.Value) (result [
 		case *types.RuntimeValue:
 			switch x := arg.Interface().(type) {
 			case issue.Issue:
-				result[ix] = &LevelExpectation{level: level, includes: []*Include{{[]Match{&IssueMatch{x, nil}}}}}
+				result[ix] = &LevelExpectation{level: level, includes: []*Include{{[]Match{&IssueMatch{x, nil, nil}}}}}
 				continue
 			case *Include:
 				result[ix] = &LevelExpectation{level: level, includes: []*Include{x}}
@@ -367,13 +504,17 @@ func (e *EvaluatesWith) CreateTest(actual interface{}) Executable {
 		if epp {
 			o = append(o, parser.EppMode)
 		}
-		actual, issues := parseAndValidate(path, tc.resolveLazyValue(source).String(), false, o...)
+		src, markers, err := expectationSource(tc.resolveLazyValue(source).String(), e.expectations)
+		if err != nil {
+			assertions.Fail(err.Error())
+		}
+		actual, issues := parseAndValidate(path, src, false, o...)
 		tc.DoWithContext(func(c pdsl.EvaluationContext) {
 			if !hasError(issues) {
 				_, evalIssues := evaluate(c, actual)
 				issues = append(issues, evalIssues...)
 			}
-			validateExpectations(assertions, e.expectations, issues, c.Logger().(*px.ArrayLogger))
+			validateExpectations(assertions, e.expectations, markers, issues, c.Logger().(*px.ArrayLogger))
 		})
 	}
 }
@@ -389,8 +530,12 @@ func (v *ValidatesWith) CreateTest(actual interface{}) Executable {
 		if epp {
 			o = append(o, parser.EppMode)
 		}
-		_, issues := parseAndValidate(path, tc.resolveLazyValue(source).String(), false, o...)
-		validateExpectations(assertions, v.expectations, issues, px.NewArrayLogger())
+		src, markers, err := expectationSource(tc.resolveLazyValue(source).String(), v.expectations)
+		if err != nil {
+			assertions.Fail(err.Error())
+		}
+		_, issues := parseAndValidate(path, src, false, o...)
+		validateExpectations(assertions, v.expectations, markers, issues, px.NewArrayLogger())
 	}
 }
 
@@ -398,10 +543,23 @@ func (v *ValidatesWith) setExample(example *Example) {
 	v.example = example
 }
 
-func validateExpectations(assertions Assertions, expectations []*Expectation, issues []issue.Reported, log *px.ArrayLogger) {
+// expectationSource returns the source to validate or evaluate and its markers. The markers are
+// stripped from the source only when an expectation refers to a marker.
+func expectationSource(source string, expectations []*Expectation) (string, map[string]*sourceMarker, error) {
+	used := false
+	for _, ex := range expectations {
+		if ex.usesMarkers() {
+			used = true
+			break
+		}
+	}
+	return markedSource(source, used)
+}
+
+func validateExpectations(assertions Assertions, expectations []*Expectation, markers map[string]*sourceMarker, issues []issue.Reported, log *px.ArrayLogger) {
 	bld := bytes.NewBufferString(``)
 	for _, ex := range expectations {
-		ex.MatchEntries(bld, log, issues)
+		ex.withMarkers(assertions, markers).MatchEntries(bld, log, issues)
 	}
 	if bld.Len() > 0 {
 		assertions.Fail(bld.String())
@@ -434,19 +592,27 @@ func init() {
 			})
 		})
 
+	// The second argument holds the expected issue arguments and the optional third argument the expected
+	// location of the issue.
 	px.NewGoConstructor(`PSpec::Issue`,
 		func(d px.Dispatch) {
-			d.Param2(types.NewGoRuntimeType((*issue.Issue)(nil)))
+			d.Param2(issueType)
 			d.OptionalParam(`Hash[String,Any]`)
+			d.OptionalParam(issueLocationType)
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				var argsMap *hash.StringHash
+				im := &IssueMatch{issue: args[0].(*types.RuntimeValue).Interface().(issue.Issue)}
 				if len(args) > 1 {
-					argsMap = hash.NewStringHash(5)
 					args[1].(*types.Hash).EachPair(func(k, v px.Value) {
-						argsMap.Put(k.String(), makeIssueArgMatch(v))
+						if im.argsMap == nil {
+							im.argsMap = hash.NewStringHash(5)
+						}
+						im.argsMap.Put(k.String(), makeIssueArgMatch(v))
 					})
 				}
-				return types.WrapRuntime(&IssueMatch{issue: args[0].(*types.RuntimeValue).Interface().(issue.Issue), argsMap: argsMap})
+				if len(args) > 2 {
+					im.location = makeIssueLocation(args[2].(*types.Hash))
+				}
+				return types.WrapRuntime(im)
 			})
 		})
 
@@ -493,7 +659,7 @@ func init() {
 		func(d px.Dispatch) {
 			d.Param(`Any`)
 			d.Function(func(c px.Context, args []px.Value) px.Value {
-				return types.WrapRuntime(&EvaluationResult{nil, args[0]})
+				return types.WrapRuntime(&EvaluationResult{c.StackTop(), nil, args[0]})
 			})
 		})
 
@@ -525,6 +691,14 @@ func init() {
 			})
 		})
 
+	px.NewGoConstructor(`PSpec::Parses_like`,
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return types.WrapRuntime(&ParseLikeResult{location: c.StackTop(), expected: args[0].String()})
+			})
+		})
+
 	px.NewGoConstructor(`PSpec::Validates_ok`,
 		func(d px.Dispatch) {
 			d.Function(func(c px.Context, args []px.Value) px.Value {
@@ -0,0 +1,74 @@
+package pspec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/pcore/px"
+)
+
+func markerExpectation(marker string) *Expectation {
+	im := &IssueMatch{location: &issueLocation{line: 1, marker: marker}}
+	return &Expectation{[]*LevelExpectation{{px.ERR, []*Include{{[]Match{im}}}, nil}}}
+}
+
+func TestMarkersAreStrippedOnlyWhenReferenced(t *testing.T) {
+	source := "$a = 'x\n ^ b'"
+	src, markers, _ := expectationSource(source, []*Expectation{markerExpectation(``)})
+	if src != source || len(markers) != 0 {
+		t.Errorf("expected the source to be kept, got %q and %d markers", src, len(markers))
+	}
+	src, markers, _ = expectationSource(source, []*Expectation{markerExpectation(``), markerExpectation(`b`)})
+	if src != `$a = 'x` || markers[`b`] == nil {
+		t.Errorf("expected the marker to be stripped, got %q and %d markers", src, len(markers))
+	}
+}
+
+// numericAssignmentSpec returns a spec that expects the numeric assignment on line 2 of its source to
+// be reported as an Issue with the given arguments
+func numericAssignmentSpec(issueArgs string) string {
+	return `
+Example('numeric assignment',
+  Given(Unindent(` + "`" + `
+    $x = 'x'
+    $1 = 'y'
+    ` + "`" + `)),
+  Validates_with(Error(Issue(VALIDATE_ILLEGAL_NUMERIC_ASSIGNMENT, ` + issueArgs + `))))
+`
+}
+
+func TestInvalidIssueLocationIsRejected(t *testing.T) {
+	for _, location := range []string{`line => 'two'`, `line => 0`, `marker => ''`, `column => 1`} {
+		t.Run(location, func(t *testing.T) {
+			rejected := false
+			func() {
+				defer func() {
+					if recover() != nil {
+						rejected = true
+					}
+				}()
+				runSpec(t, numericAssignmentSpec(`{}, { `+location+` }`))
+			}()
+			if !rejected {
+				t.Errorf("expected %s to be rejected", location)
+			}
+		})
+	}
+}
+
+func TestIssueLocationMismatchFails(t *testing.T) {
+	out, ok := runSpec(t, numericAssignmentSpec(`{}, { line => 1 }`))
+	if ok || !strings.Contains(out, `at line 1`) {
+		t.Errorf("expected the example to fail on the line, got:\n%s", out)
+	}
+	if out, ok = runSpec(t, numericAssignmentSpec(`{}, { line => 2, pos => 1 }`)); !ok {
+		t.Errorf("expected the example to pass, got:\n%s", out)
+	}
+}
+
+func TestLocationKeysOfIssueArgumentsAreArguments(t *testing.T) {
+	out, ok := runSpec(t, numericAssignmentSpec(`{ line => 2 }`))
+	if ok {
+		t.Errorf("expected line to be matched as an issue argument, got:\n%s", out)
+	}
+}
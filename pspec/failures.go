@@ -0,0 +1,157 @@
+package pspec
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFailureCache is the path of the file that records the failed examples when the OnlyFailures
+// option is used without a FailureCache option
+const DefaultFailureCache = `.pspec-failures`
+
+type failureRecorder struct {
+	lock     sync.Mutex
+	path     string
+	failures map[string]bool
+	passed   map[string]bool
+}
+
+// newFailureRecorder returns a Reporter that updates the file at the given path when the test run is done.
+// The identities of the failed examples are added to the file and those of the examples that passed are
+// removed. Examples that were not run keep their entries so that a filtered run doesn't forget them.
+func newFailureRecorder(path string) Reporter {
+	return &failureRecorder{path: path, failures: make(map[string]bool), passed: make(map[string]bool)}
+}
+
+// exampleIdentity returns the string that identifies an example across test runs. It consists of the
+// file that declares the example followed by the names of the enclosing groups and the name of the
+// example, all separated by tabs.
+func exampleIdentity(path []string, example *TestExecutable) string {
+	file := ``
+	if loc := example.Node().Location(); loc != nil {
+		file = loc.File()
+	}
+	return strings.Join(append([]string{file}, childPath(path, example.Name())...), "\t")
+}
+
+// readFailures reads the identities of the examples that are recorded in the file at the given path. A
+// file that doesn't exist contains no identities.
+func readFailures(path string) (map[string]bool, error) {
+	failures := make(map[string]bool)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return failures, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := s.Text(); line != `` {
+			failures[line] = true
+		}
+	}
+	return failures, s.Err()
+}
+
+// filterByFailures returns the tests that contain examples with the given identities
+func filterByFailures(tests []Test, failures map[string]bool) []Test {
+	return selectTests(tests, nil, func(ancestors []Test, test Test) bool {
+		if e, ok := test.(*TestExecutable); ok {
+			return failures[exampleIdentity(testNames(ancestors), e)]
+		}
+		return false
+	})
+}
+
+func (r *failureRecorder) GroupStarted(path []string, group *TestGroup) {
+}
+
+func (r *failureRecorder) GroupFinished(path []string, group *TestGroup, elapsed time.Duration) {
+}
+
+// GroupFailed records all examples of the group as failed since a rerun of any one of them also reruns
+// the hooks of the group
+func (r *failureRecorder) GroupFailed(path []string, group *TestGroup, message string) {
+	r.lock.Lock()
+	r.recordGroup(childPath(path, group.Name()), group.Tests())
+	r.lock.Unlock()
+}
+
+func (r *failureRecorder) recordGroup(path []string, tests []Test) {
+	for _, test := range tests {
+		switch test := test.(type) {
+		case *TestExecutable:
+			r.failures[exampleIdentity(path, test)] = true
+		case *TestGroup:
+			r.recordGroup(childPath(path, test.Name()), test.Tests())
+		}
+	}
+}
+
+func (r *failureRecorder) ExampleStarted(path []string, example *TestExecutable) {
+}
+
+func (r *failureRecorder) ExamplePassed(path []string, example *TestExecutable, elapsed time.Duration) {
+	r.lock.Lock()
+	r.passed[exampleIdentity(path, example)] = true
+	r.lock.Unlock()
+}
+
+func (r *failureRecorder) ExampleFailed(path []string, example *TestExecutable, elapsed time.Duration, message string) {
+	r.lock.Lock()
+	r.failures[exampleIdentity(path, example)] = true
+	r.lock.Unlock()
+}
+
+func (r *failureRecorder) ExampleSkipped(path []string, example *TestExecutable, reason string) {
+}
+
+func (r *failureRecorder) ExamplePending(path []string, example *TestExecutable, elapsed time.Duration, reason string) {
+	r.lock.Lock()
+	r.passed[exampleIdentity(path, example)] = true
+	r.lock.Unlock()
+}
+
+func (r *failureRecorder) TearDownFailed(path []string, example *TestExecutable, err error) {
+}
+
+func (r *failureRecorder) Done() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	failures, err := readFailures(r.path)
+	if err != nil {
+		return err
+	}
+	for id := range r.passed {
+		delete(failures, id)
+	}
+	for id := range r.failures {
+		failures[id] = true
+	}
+	ids := make([]string, 0, len(failures))
+	for id := range failures {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	f, err := os.Create(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, id := range ids {
+		w.WriteString(id)
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
@@ -0,0 +1,57 @@
+package pspec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFailureCacheKeepsEntriesOfExamplesThatDidNotRun(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, `failures`)
+	if err = ioutil.WriteFile(cache, []byte("a.pspec\tkept\na.pspec\tfixed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newFailureRecorder(cache)
+	r.ExamplePassed(nil, testExample(`fixed`, `a.pspec`, 1), 0)
+	r.ExampleFailed(nil, testExample(`broken`, `a.pspec`, 2), 0, `boom`)
+	if err = r.Done(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a.pspec\tbroken\na.pspec\tkept\n"; string(content) != expected {
+		t.Errorf("expected %q, got %q", expected, string(content))
+	}
+}
+
+func TestOnlyFailuresWithoutRecordedFailuresRunsAllExamples(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, `failures`)
+
+	out, ok := runSpec(t, `
+Example('passes',
+  Given('1 + 1'),
+  Evaluates_to(2))
+`, FailureCache(cache), OnlyFailures())
+	if !ok {
+		t.Fatalf("expected the run to pass, got:\n%s", out)
+	}
+	if !strings.Contains(out, `No failed examples are recorded in `+cache+`, running all examples`) ||
+		!strings.Contains(out, `1 examples, 0 failures`) {
+		t.Errorf("expected all examples to run, got:\n%s", out)
+	}
+}
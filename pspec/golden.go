@@ -0,0 +1,188 @@
+package pspec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// goldenUpdater is implemented by assertions that rewrite failing expectations in the source of the
+	// spec instead of failing
+	goldenUpdater interface {
+		updateGolden(location issue.Location, replacement string) bool
+
+		// updating returns true when expectations are updated rather than failed
+		updating() bool
+
+		// snapshotWritten records that a snapshot was created or replaced
+		snapshotWritten()
+	}
+
+	// goldenUpdates are the replacements of expectation arguments that are collected during a run
+	goldenUpdates struct {
+		lock    sync.Mutex
+		updates map[string][]*goldenUpdate
+
+		// skipped are the locations of the expectations that could not be updated
+		skipped []string
+
+		// snapshots is the number of snapshots that were created or replaced
+		snapshots int
+	}
+
+	goldenUpdate struct {
+		line        int
+		pos         int
+		replacement string
+	}
+
+	// goldenSpan is the byte span of an expectation argument that will be replaced
+	goldenSpan struct {
+		offset      int
+		length      int
+		replacement string
+	}
+)
+
+func newGoldenUpdates() *goldenUpdates {
+	return &goldenUpdates{updates: make(map[string][]*goldenUpdate)}
+}
+
+// updateGolden asks the given assertions to replace the first argument of the expectation call at the given
+// location with the given replacement. It returns true if the replacement was accepted, in which case the
+// expectation must not fail.
+func updateGolden(assertions Assertions, location issue.Location, replacement string) bool {
+	if u, ok := assertions.(goldenUpdater); ok && location != nil {
+		return u.updateGolden(location, replacement)
+	}
+	return false
+}
+
+func (g *goldenUpdates) snapshotWritten() {
+	g.lock.Lock()
+	g.snapshots++
+	g.lock.Unlock()
+}
+
+func (g *goldenUpdates) add(location issue.Location, replacement string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	file := location.File()
+	for _, u := range g.updates[file] {
+		if u.line == location.Line() && u.pos == location.Pos() {
+			// An expectation that is combined with several inputs keeps the first replacement
+			return
+		}
+	}
+	g.updates[file] = append(g.updates[file], &goldenUpdate{location.Line(), location.Pos(), replacement})
+}
+
+// write applies the collected replacements to the spec files and returns the number of replacements
+// that were made
+func (g *goldenUpdates) write() (int, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	count := 0
+	for file, updates := range g.updates {
+		n, skipped, err := rewriteFile(file, updates)
+		if err != nil {
+			return count, err
+		}
+		count += n
+		g.skipped = append(g.skipped, skipped...)
+	}
+	g.updates = make(map[string][]*goldenUpdate)
+	return count, nil
+}
+
+// rewriteFile replaces the first argument of the expectation calls in the given file that are appointed
+// by the given updates. An argument that is a heredoc is not replaced since its text isn't contiguous with
+// its tag. The number of replacements is returned together with the locations of the skipped arguments.
+func rewriteFile(file string, updates []*goldenUpdate) (int, []string, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, nil, err
+	}
+	content := string(bytes)
+	expr, err := parser.CreatePspecParser().Parse(file, content, false)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	spans := make([]*goldenSpan, 0, len(updates))
+	skipped := make([]string, 0)
+	expr.AllContents([]parser.Expression{}, func(path []parser.Expression, e parser.Expression) {
+		call, ok := e.(*parser.CallNamedFunctionExpression)
+		if !ok || len(call.Arguments()) == 0 {
+			return
+		}
+		for _, u := range updates {
+			if call.Line() == u.line && call.Pos() == u.pos {
+				arg := call.Arguments()[0]
+				if _, ok := arg.(*parser.HeredocExpression); ok {
+					skipped = append(skipped, fmt.Sprintf(`%s:%d`, file, u.line))
+					continue
+				}
+				spans = append(spans, &goldenSpan{arg.ByteOffset(), arg.ByteLength(), u.replacement})
+			}
+		}
+	})
+	if len(spans) == 0 {
+		return 0, skipped, nil
+	}
+
+	// Replace from the end so that the offsets of the remaining spans stay valid
+	sort.Slice(spans, func(i, j int) bool { return spans[i].offset > spans[j].offset })
+	for _, s := range spans {
+		content = content[:s.offset] + s.replacement + content[s.offset+s.length:]
+	}
+	return len(spans), skipped, ioutil.WriteFile(file, []byte(content), 0644)
+}
+
+// isLazy returns true if the given value is a lazy value that is resolved when the example runs
+func isLazy(v px.Value) bool {
+	if rt, ok := v.(*types.RuntimeValue); ok {
+		_, ok = rt.Interface().(LazyValue)
+		return ok
+	}
+	return false
+}
+
+// puppetString returns the given string as a single quoted Puppet string literal
+func puppetString(s string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + `'`
+}
+
+// goldenMessage writes the collected replacements to the spec files and returns a message that tells
+// how many expectations were updated and which ones could not be updated, or an empty string when
+// nothing was updated
+func (o *options) goldenMessage() (string, error) {
+	if o.golden == nil {
+		return ``, nil
+	}
+	n, err := o.golden.write()
+	if err != nil {
+		return ``, err
+	}
+	n += o.golden.snapshots
+	msgs := make([]string, 0, 2)
+	if n > 0 {
+		msgs = append(msgs, fmt.Sprintf(`Updated %d expectations`, n))
+	}
+	if len(o.golden.skipped) > 0 {
+		sort.Strings(o.golden.skipped)
+		msgs = append(msgs, fmt.Sprintf(`Could not update the heredoc arguments of %d expectations: %s`,
+			len(o.golden.skipped), strings.Join(o.golden.skipped, `, `)))
+	}
+	return strings.Join(msgs, "\n"), nil
+}
@@ -0,0 +1,127 @@
+package pspec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+const goldenSpec = `Example('a',
+  Given('1 + 2'),
+  Evaluates_to(4))
+Example('b',
+  Given('2 + 2'),
+  Evaluates_to(5))
+Example('c',
+  Given('1'),
+  Parses_to(@(PN)))
+  (int 2)
+  |-PN
+Example('d',
+  Given('3 + 3'),
+  Evaluates_to(6))
+`
+
+// expectationUpdates returns updates for the expectation calls of the given content, in the order that they
+// are declared, that replace their first argument with the given replacements
+func expectationUpdates(t *testing.T, file, content string, replacements ...string) []*goldenUpdate {
+	t.Helper()
+	expr, err := parser.CreatePspecParser().Parse(file, content, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := make([]*goldenUpdate, 0, len(replacements))
+	expr.AllContents([]parser.Expression{}, func(path []parser.Expression, e parser.Expression) {
+		if call, ok := e.(*parser.CallNamedFunctionExpression); ok {
+			if qr, ok := call.Functor().(*parser.QualifiedReference); ok && (qr.Name() == `Evaluates_to` || qr.Name() == `Parses_to`) {
+				updates = append(updates, &goldenUpdate{call.Line(), call.Pos(), replacements[len(updates)]})
+			}
+		}
+	})
+	if len(updates) != len(replacements) {
+		t.Fatalf(`expected %d expectations, found %d`, len(replacements), len(updates))
+	}
+	return updates
+}
+
+func TestRewriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `test.pspec`)
+	if err = ioutil.WriteFile(file, []byte(goldenSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The expectation of example 'd' is correct and is therefore not updated
+	updates := expectationUpdates(t, file, goldenSpec, `3`, `4`, `'(int 1)'`, `6`)[:3]
+	n, skipped, err := rewriteFile(file, updates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf(`expected 2 replacements, got %d`, n)
+	}
+	if expected := []string{file + `:9`}; !reflect.DeepEqual(expected, skipped) {
+		t.Errorf(`expected skipped %v, got %v`, expected, skipped)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `Example('a',
+  Given('1 + 2'),
+  Evaluates_to(3))
+Example('b',
+  Given('2 + 2'),
+  Evaluates_to(4))
+Example('c',
+  Given('1'),
+  Parses_to(@(PN)))
+  (int 2)
+  |-PN
+Example('d',
+  Given('3 + 3'),
+  Evaluates_to(6))
+`
+	if string(content) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, content)
+	}
+}
+
+func TestRewriteFileWithLongerReplacementsBeforeShorterOnes(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `test.pspec`)
+	if err = ioutil.WriteFile(file, []byte(goldenSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := expectationUpdates(t, file, goldenSpec, `'three'`, `'four'`, `'(int 1)'`, `'six'`)
+	updates = append(updates[:2], updates[3])
+	if n, _, err := rewriteFile(file, updates); err != nil || n != 3 {
+		t.Fatalf(`expected 3 replacements, got %d, %v`, n, err)
+	}
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = parser.CreatePspecParser().Parse(file, string(content), false); err != nil {
+		t.Errorf("rewritten file doesn't parse: %s\n%s", err.Error(), content)
+	}
+	for _, s := range []string{`Evaluates_to('three')`, `Evaluates_to('four')`, `Evaluates_to('six')`, "Parses_to(@(PN)))\n  (int 2)\n  |-PN"} {
+		if !strings.Contains(string(content), s) {
+			t.Errorf("expected the rewritten file to contain %q, got\n%s", s, content)
+		}
+	}
+}
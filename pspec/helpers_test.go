@@ -0,0 +1,67 @@
+package pspec
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testLocation is an issue.Location used by the unit tests
+type testLocation struct {
+	file string
+	line int
+	pos  int
+}
+
+func (l *testLocation) File() string {
+	return l.file
+}
+
+func (l *testLocation) Line() int {
+	return l.line
+}
+
+func (l *testLocation) Pos() int {
+	return l.pos
+}
+
+// testExample returns an executable for an example with the given description that is declared on the
+// given line of the given file
+func testExample(description, file string, line int) *TestExecutable {
+	return &TestExecutable{testNode{newExample(description, &testLocation{file, line, 1}, nil, nil)}, nil}
+}
+
+// testGroup returns a group with the given description that is declared on the given line of the given file
+func testGroup(description, file string, line int, tests ...Test) *TestGroup {
+	return &TestGroup{testNode: testNode{newExamples(description, &testLocation{file, line, 1}, nil, nil)}, tests: tests}
+}
+
+// runSpec writes the given spec to a file named test.pspec in a temporary directory, runs it with a verbose
+// Runner using the given options, and returns the output of the Runner together with the result of the run
+func runSpec(t *testing.T, spec string, opts ...Option) (string, bool) {
+	t.Helper()
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	return runSpecIn(t, dir, spec, opts...)
+}
+
+// runSpecIn is like runSpec but writes the spec to the given directory
+func runSpecIn(t *testing.T, dir, spec string, opts ...Option) (string, bool) {
+	t.Helper()
+	file := filepath.Join(dir, `test.pspec`)
+	if err := ioutil.WriteFile(file, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests, err := LoadTests([]string{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := bytes.NewBufferString(``)
+	ok := NewRunner(out, true, opts...).Run(tests)
+	return out.String(), ok
+}
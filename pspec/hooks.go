@@ -0,0 +1,118 @@
+package pspec
+
+import (
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	hookKind int
+
+	// Hook is a Before_each, After_each, Before_all, or After_all declaration of an Examples node
+	Hook struct {
+		kind   hookKind
+		inputs []Input
+	}
+
+	// hookResult is the Result used when creating the executables of a hook. It evaluates
+	// the given source and fails on any error.
+	hookResult struct{}
+)
+
+const (
+	beforeEach = hookKind(iota)
+	afterEach
+	beforeAll
+	afterAll
+)
+
+var hookNames = map[hookKind]string{
+	beforeEach: `Before_each`,
+	afterEach:  `After_each`,
+	beforeAll:  `Before_all`,
+	afterAll:   `After_all`,
+}
+
+func (h *Hook) run(tc *TestContext, assertions Assertions) {
+	for _, input := range h.inputs {
+		for _, test := range input.CreateTests(&hookResult{}) {
+			test(tc, assertions)
+		}
+	}
+}
+
+func (h *hookResult) CreateTest(actual interface{}) Executable {
+	path, source, epp := pathContentAndEpp(actual)
+
+	return func(tc *TestContext, assertions Assertions) {
+		o := tc.ParserOptions()
+		if epp {
+			o = append(o, parser.EppMode)
+		}
+		expr, issues := parseAndValidate(path, tc.resolveLazyValue(source).String(), false, o...)
+		failOnError(assertions, issues)
+		tc.DoWithContext(func(c pdsl.EvaluationContext) {
+			_, evalIssues := evaluate(c, expr)
+			failOnError(assertions, evalIssues)
+		})
+	}
+}
+
+func (h *hookResult) setExample(example *Example) {
+}
+
+// runHooks runs all hooks of the given kind that are declared by the node of this context. After
+// hooks are guaranteed to run even when a preceding hook fails.
+func (tc *TestContext) runHooks(kind hookKind, assertions Assertions) {
+	hooks := tc.node.hooks(kind)
+	if kind == afterAll {
+		for i := len(hooks) - 1; i >= 0; i-- {
+			defer hooks[i].run(tc, assertions)
+		}
+		return
+	}
+	for _, h := range hooks {
+		h.run(tc, assertions)
+	}
+}
+
+// inheritedHooks returns the hooks of the given kind that are declared by the node of this
+// context and by the nodes of all enclosing contexts, outermost first.
+func (tc *TestContext) inheritedHooks(kind hookKind) []*Hook {
+	var hooks []*Hook
+	if tc.parent != nil {
+		hooks = tc.parent.inheritedHooks(kind)
+	} else {
+		hooks = make([]*Hook, 0)
+	}
+	return append(hooks, tc.node.hooks(kind)...)
+}
+
+// beforeAllFailures returns the failures of the Before_all hooks of this context and its enclosing contexts
+func (tc *TestContext) beforeAllFailures() []string {
+	for c := tc; c != nil; c = c.parent {
+		if len(c.hookFailures) > 0 {
+			return c.hookFailures
+		}
+	}
+	return nil
+}
+
+func newHookConstructor(kind hookKind) {
+	px.NewGoConstructor(`PSpec::`+hookNames[kind],
+		func(d px.Dispatch) {
+			d.RepeatedParam2(types.NewVariantType(types.DefaultStringType(), types.NewGoRuntimeType((*Input)(nil)), types.NewGoRuntimeType((*LazyValue)(nil))))
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				return types.WrapRuntime(&Hook{kind, makeInputs(args)})
+			})
+		})
+}
+
+func init() {
+	newHookConstructor(beforeEach)
+	newHookConstructor(afterEach)
+	newHookConstructor(beforeAll)
+	newHookConstructor(afterAll)
+}
@@ -0,0 +1,121 @@
+package pspec
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAfterEachRunsAfterFailedAssertion(t *testing.T) {
+	out, ok := runSpec(t, `
+Examples('hooks',
+  After_each('fail("after each ran")'),
+  Example('aborts on a syntax error',
+    Given('1 +'),
+    Evaluates_to(2)))
+`)
+	if ok {
+		t.Fatalf("expected the run to fail, got:\n%s", out)
+	}
+	syntax := strings.Index(out, `test.pspec:4:`)
+	after := strings.Index(out, `after each ran`)
+	if syntax < 0 || after < syntax {
+		t.Errorf("expected the failure of the example followed by the failure of After_each, got:\n%s", out)
+	}
+	if !strings.Contains(out, `1 examples, 1 failures`) {
+		t.Errorf("expected 1 failed example, got:\n%s", out)
+	}
+}
+
+func TestFailedAfterAllIsNotCountedAsExample(t *testing.T) {
+	out, ok := runSpec(t, `
+Examples('hooks',
+  After_all('fail("after all ran")'),
+  Example('passes',
+    Given('1 + 1'),
+    Evaluates_to(2)))
+`)
+	if ok {
+		t.Fatalf("expected the run to fail, got:\n%s", out)
+	}
+	if !strings.Contains(out, `after all ran`) || !strings.Contains(out, `1 examples, 0 failures, 1 failed hooks`) {
+		t.Errorf("expected a failed After_all hook and no failed examples, got:\n%s", out)
+	}
+}
+
+func TestFailedAfterAllIsReported(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	junitPath := filepath.Join(dir, `junit.xml`)
+	jsonPath := filepath.Join(dir, `events.json`)
+	cache := filepath.Join(dir, `failures`)
+
+	out, ok := runSpecIn(t, dir, `
+Examples('hooks',
+  After_all('fail("after all ran")'),
+  Example('passes',
+    Given('1 + 1'),
+    Evaluates_to(2)))
+`, JUnitReport(junitPath), JSONReport(jsonPath), FailureCache(cache))
+	if ok {
+		t.Fatalf("expected the run to fail, got:\n%s", out)
+	}
+
+	content, err := ioutil.ReadFile(junitPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := &junitSuites{}
+	if err = xml.Unmarshal(content, all); err != nil {
+		t.Fatal(err)
+	}
+	if all.Tests != 2 || all.Failures != 1 || len(all.Suites) != 1 {
+		t.Fatalf("expected 2 tests and 1 failure in 1 suite, got:\n%s", content)
+	}
+	if c := all.Suites[0].Cases[1]; c.Name != `After_all` || c.ClassName != `hooks` || c.Failure == nil ||
+		!strings.Contains(c.Failure.Text, `after all ran`) {
+		t.Errorf("unexpected hook case %+v", c)
+	}
+
+	f, err := os.Open(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var groupFail, done *jsonEvent
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		e := &jsonEvent{}
+		if err = json.Unmarshal(lines.Bytes(), e); err != nil {
+			t.Fatal(err)
+		}
+		switch e.Event {
+		case `group-fail`:
+			groupFail = e
+		case `done`:
+			done = e
+		}
+	}
+	if groupFail == nil || groupFail.Name != `hooks` || !strings.Contains(groupFail.Message, `after all ran`) {
+		t.Errorf("unexpected group-fail event %+v", groupFail)
+	}
+	if done == nil || done.Hooks == nil || *done.Hooks != 1 {
+		t.Errorf("unexpected done event %+v", done)
+	}
+
+	content, err = ioutil.ReadFile(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "\thooks\tpasses\n") {
+		t.Errorf("expected the examples of the group to be recorded as failed, got %q", string(content))
+	}
+}
@@ -0,0 +1,92 @@
+package pspec
+
+import (
+	"sync"
+
+	"github.com/lyraproj/pcore/pcore"
+)
+
+type (
+	// settingsGuard is a readers-writer lock that protects the global pcore settings. Examples that run
+	// concurrently are readers while examples that change the settings, or must run alone for other
+	// reasons, are writers. An example that times out is left running and keeps its hold until it returns.
+	// Those who would have to wait for such a stalled hold give up rather than waiting forever.
+	settingsGuard struct {
+		cond           *sync.Cond
+		readers        int
+		writer         bool
+		waitingWriters int
+		stalledReaders int
+		stalledWriter  bool
+	}
+
+	// settingsHold is a hold of the settingsLock
+	settingsHold struct {
+		write   bool
+		stalled bool
+	}
+)
+
+var settingsLock = &settingsGuard{cond: sync.NewCond(&sync.Mutex{})}
+
+// acquire waits until the lock can be held for writing, or for reading when write is false, and returns
+// the hold. Nil is returned when the lock would have to be waited for until a stalled hold is released.
+// A writer starts and ends with the default pcore settings.
+func (g *settingsGuard) acquire(write bool) *settingsHold {
+	g.cond.L.Lock()
+	defer g.cond.L.Unlock()
+	if write {
+		g.waitingWriters++
+		defer func() {
+			g.waitingWriters--
+			g.cond.Broadcast()
+		}()
+	}
+	for {
+		if g.stalledWriter || write && g.stalledReaders > 0 {
+			return nil
+		}
+		if write && !g.writer && g.readers == 0 {
+			g.writer = true
+			pcore.Reset()
+			return &settingsHold{write: true}
+		}
+		if !write && !g.writer && g.waitingWriters == 0 {
+			g.readers++
+			return &settingsHold{}
+		}
+		g.cond.Wait()
+	}
+}
+
+// stall marks the given hold as stalled. Those who wait for it give up.
+func (g *settingsGuard) stall(h *settingsHold) {
+	g.cond.L.Lock()
+	defer g.cond.L.Unlock()
+	h.stalled = true
+	if h.write {
+		g.stalledWriter = true
+	} else {
+		g.stalledReaders++
+	}
+	g.cond.Broadcast()
+}
+
+// release ends the given hold
+func (g *settingsGuard) release(h *settingsHold) {
+	g.cond.L.Lock()
+	defer g.cond.L.Unlock()
+	if h.write {
+		pcore.Reset()
+		g.writer = false
+		if h.stalled {
+			g.stalledWriter = false
+		}
+	} else {
+		g.readers--
+		if h.stalled {
+			g.stalledReaders--
+		}
+	}
+	g.cond.Broadcast()
+}
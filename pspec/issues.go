@@ -3,11 +3,14 @@ package pspec
 import "github.com/lyraproj/issue/issue"
 
 const (
-	GetOfUnknownVariable = `PSPEC_GET_OF_UNKNOWN_VARIABLE`
-	InvalidFileContent   = `PSPEC_INVALID_FILE_CONTENT`
-	FormatNotString      = `PSPEC_FORMAT_NOT_STRING`
-	ValueNotHash         = `PSPEC_VALUE_NOT_HASH`
-	PnParseError         = `PSPEC_PN_PARSE_ERROR`
+	GetOfUnknownVariable    = `PSPEC_GET_OF_UNKNOWN_VARIABLE`
+	InvalidFileContent      = `PSPEC_INVALID_FILE_CONTENT`
+	FormatNotString         = `PSPEC_FORMAT_NOT_STRING`
+	ValueNotHash            = `PSPEC_VALUE_NOT_HASH`
+	PnParseError            = `PSPEC_PN_PARSE_ERROR`
+	UnknownSharedExamples   = `PSPEC_UNKNOWN_SHARED_EXAMPLES`
+	DuplicateSharedExamples = `PSPEC_DUPLICATE_SHARED_EXAMPLES`
+	RecursiveSharedExamples = `PSPEC_RECURSIVE_SHARED_EXAMPLES`
 )
 
 func init() {
@@ -16,4 +19,7 @@ func init() {
 	issue.Hard(InvalidFileContent, `Cannot create file content from a value of type %<value>T`)
 	issue.Hard(ValueNotHash, `%{type} does not contain a Hash`)
 	issue.Hard(PnParseError, `PN parse error: %{detail}`)
+	issue.Hard(UnknownSharedExamples, `No shared examples named '%{name}' have been declared`)
+	issue.Hard(DuplicateSharedExamples, `Shared examples named '%{name}' have already been declared`)
+	issue.Hard(RecursiveSharedExamples, `Shared examples named '%{name}' behave like themselves: %{cycle}`)
 }
@@ -0,0 +1,178 @@
+package pspec
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	jsonReporter struct {
+		lock    sync.Mutex
+		path    string
+		out     io.Writer
+		encoder *json.Encoder
+		err     error
+		passed  int
+		failed  int
+		pending int
+		skipped int
+		hooks   int
+	}
+
+	jsonEvent struct {
+		Event   string   `json:"event"`
+		Time    string   `json:"time"`
+		Path    []string `json:"path,omitempty"`
+		Name    string   `json:"name,omitempty"`
+		Elapsed *float64 `json:"elapsed,omitempty"`
+		Message string   `json:"message,omitempty"`
+		File    string   `json:"file,omitempty"`
+		Line    int      `json:"line,omitempty"`
+		Pos     int      `json:"pos,omitempty"`
+		Passed  *int     `json:"passed,omitempty"`
+		Failed  *int     `json:"failed,omitempty"`
+		Pending *int     `json:"pending,omitempty"`
+		Skipped *int     `json:"skipped,omitempty"`
+		Hooks   *int     `json:"failedHooks,omitempty"`
+	}
+)
+
+// NewJSONReporter returns a Reporter that writes one JSON object per line to the given writer
+// for each event of the test run. The "event" property of an object is one of "group-start",
+// "group-end", "group-fail", "example-start", "pass", "fail", "skip", "pending", "teardown-error", or
+// "done". A "group-fail" event is written when the After_all hooks of a group failed.
+func NewJSONReporter(out io.Writer) Reporter {
+	return &jsonReporter{out: out, encoder: json.NewEncoder(out)}
+}
+
+// newJSONFileReporter returns a JSON reporter that writes to the file at the given path. The
+// file is created when the first event is written. A path of "-" denotes stdout.
+func newJSONFileReporter(path string) Reporter {
+	if path == `-` {
+		return NewJSONReporter(os.Stdout)
+	}
+	return &jsonReporter{path: path}
+}
+
+func (r *jsonReporter) GroupStarted(path []string, group *TestGroup) {
+	r.write(&jsonEvent{Event: `group-start`, Path: path, Name: group.Name()})
+}
+
+func (r *jsonReporter) GroupFinished(path []string, group *TestGroup, elapsed time.Duration) {
+	r.write(&jsonEvent{Event: `group-end`, Path: path, Name: group.Name(), Elapsed: seconds(elapsed)})
+}
+
+func (r *jsonReporter) GroupFailed(path []string, group *TestGroup, message string) {
+	e := &jsonEvent{Event: `group-fail`, Path: path, Name: group.Name(), Message: message}
+	if loc := group.Node().Location(); loc != nil {
+		e.File = loc.File()
+		e.Line = loc.Line()
+		e.Pos = loc.Pos()
+	}
+	r.write(e)
+	r.lock.Lock()
+	r.hooks++
+	r.lock.Unlock()
+}
+
+func (r *jsonReporter) ExampleStarted(path []string, example *TestExecutable) {
+	r.write(r.exampleEvent(`example-start`, path, example))
+}
+
+func (r *jsonReporter) ExamplePassed(path []string, example *TestExecutable, elapsed time.Duration) {
+	e := r.exampleEvent(`pass`, path, example)
+	e.Elapsed = seconds(elapsed)
+	r.write(e)
+	r.lock.Lock()
+	r.passed++
+	r.lock.Unlock()
+}
+
+func (r *jsonReporter) ExampleFailed(path []string, example *TestExecutable, elapsed time.Duration, message string) {
+	e := r.exampleEvent(`fail`, path, example)
+	e.Elapsed = seconds(elapsed)
+	e.Message = message
+	r.write(e)
+	r.lock.Lock()
+	r.failed++
+	r.lock.Unlock()
+}
+
+func (r *jsonReporter) ExampleSkipped(path []string, example *TestExecutable, reason string) {
+	e := r.exampleEvent(`skip`, path, example)
+	e.Message = reason
+	r.write(e)
+	r.lock.Lock()
+	r.skipped++
+	r.lock.Unlock()
+}
+
+func (r *jsonReporter) ExamplePending(path []string, example *TestExecutable, elapsed time.Duration, reason string) {
+	e := r.exampleEvent(`pending`, path, example)
+	e.Elapsed = seconds(elapsed)
+	e.Message = reason
+	r.write(e)
+	r.lock.Lock()
+	r.pending++
+	r.lock.Unlock()
+}
+
+func (r *jsonReporter) TearDownFailed(path []string, example *TestExecutable, err error) {
+	e := r.exampleEvent(`teardown-error`, path, example)
+	e.Message = err.Error()
+	r.write(e)
+}
+
+func (r *jsonReporter) Done() error {
+	r.lock.Lock()
+	passed, failed, pending, skipped, hooks := r.passed, r.failed, r.pending, r.skipped, r.hooks
+	r.lock.Unlock()
+
+	r.write(&jsonEvent{Event: `done`, Passed: &passed, Failed: &failed, Pending: &pending, Skipped: &skipped, Hooks: &hooks})
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if c, ok := r.out.(io.Closer); ok && r.path != `` {
+		if err := c.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+func (r *jsonReporter) exampleEvent(event string, path []string, example *TestExecutable) *jsonEvent {
+	e := &jsonEvent{Event: event, Path: path, Name: example.Name()}
+	if loc := example.Node().Location(); loc != nil {
+		e.File = loc.File()
+		e.Line = loc.Line()
+		e.Pos = loc.Pos()
+	}
+	return e
+}
+
+func (r *jsonReporter) write(e *jsonEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	if r.encoder == nil {
+		var f *os.File
+		if f, r.err = os.Create(r.path); r.err != nil {
+			return
+		}
+		r.out = f
+		r.encoder = json.NewEncoder(f)
+	}
+	e.Time = time.Now().Format(time.RFC3339Nano)
+	r.err = r.encoder.Encode(e)
+}
+
+func seconds(d time.Duration) *float64 {
+	s := d.Seconds()
+	return &s
+}
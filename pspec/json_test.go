@@ -0,0 +1,75 @@
+package pspec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJSONReport(t *testing.T) {
+	pass := testExample(`passes`, `a.pspec`, 1)
+	fail := testExample(`fails`, `a.pspec`, 3)
+	skip := testExample(`is skipped`, `a.pspec`, 5)
+	inner := testGroup(`inner`, `a.pspec`, 4, skip)
+	outer := testGroup(`outer`, `a.pspec`, 2, fail, inner)
+
+	out := bytes.NewBufferString(``)
+	r := NewJSONReporter(out)
+	r.ExampleStarted([]string{}, pass)
+	r.ExamplePassed([]string{}, pass, time.Second)
+	r.GroupStarted([]string{}, outer)
+	r.ExampleStarted([]string{`outer`}, fail)
+	r.ExampleFailed([]string{`outer`}, fail, time.Second, `expected 1, got 2`)
+	r.TearDownFailed([]string{`outer`}, fail, errors.New(`cleanup failed`))
+	r.GroupStarted([]string{`outer`}, inner)
+	r.ExampleSkipped([]string{`outer`, `inner`}, skip, `not now`)
+	r.GroupFinished([]string{`outer`}, inner, time.Second)
+	r.GroupFinished([]string{}, outer, 2*time.Second)
+	if err := r.Done(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make([]*jsonEvent, 0)
+	lines := bufio.NewScanner(out)
+	for lines.Scan() {
+		e := &jsonEvent{}
+		if err := json.Unmarshal(lines.Bytes(), e); err != nil {
+			t.Fatalf(`line %q is not a JSON object: %s`, lines.Text(), err)
+		}
+		if e.Time == `` {
+			t.Errorf(`event %s has no time`, e.Event)
+		}
+		events = append(events, e)
+	}
+
+	kinds := make([]string, len(events))
+	for i, e := range events {
+		kinds[i] = e.Event
+	}
+	expected := []string{`example-start`, `pass`, `group-start`, `example-start`, `fail`, `teardown-error`,
+		`group-start`, `skip`, `group-end`, `group-end`, `done`}
+	if !reflect.DeepEqual(expected, kinds) {
+		t.Fatalf(`expected events %v, got %v`, expected, kinds)
+	}
+
+	if e := events[4]; e.Name != `fails` || !reflect.DeepEqual(e.Path, []string{`outer`}) || e.File != `a.pspec` || e.Line != 3 ||
+		e.Message != `expected 1, got 2` || e.Elapsed == nil || *e.Elapsed != 1 {
+		t.Errorf(`unexpected fail event %+v`, e)
+	}
+	if e := events[5]; e.Message != `cleanup failed` {
+		t.Errorf(`unexpected teardown-error event %+v`, e)
+	}
+	if e := events[7]; e.Name != `is skipped` || !reflect.DeepEqual(e.Path, []string{`outer`, `inner`}) || e.Message != `not now` {
+		t.Errorf(`unexpected skip event %+v`, e)
+	}
+	if e := events[9]; e.Name != `outer` || len(e.Path) != 0 || e.Elapsed == nil || *e.Elapsed != 2 {
+		t.Errorf(`unexpected group-end event %+v`, e)
+	}
+	if e := events[10]; *e.Passed != 1 || *e.Failed != 1 || *e.Pending != 0 || *e.Skipped != 1 {
+		t.Errorf(`unexpected done event %+v`, e)
+	}
+}
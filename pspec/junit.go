@@ -0,0 +1,213 @@
+package pspec
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	junitReporter struct {
+		lock   sync.Mutex
+		path   string
+		suites []*junitSuite
+	}
+
+	junitSuites struct {
+		XMLName  xml.Name      `xml:"testsuites"`
+		Tests    int           `xml:"tests,attr"`
+		Failures int           `xml:"failures,attr"`
+		Skipped  int           `xml:"skipped,attr"`
+		Time     string        `xml:"time,attr"`
+		Suites   []*junitSuite `xml:"testsuite"`
+	}
+
+	junitSuite struct {
+		XMLName  xml.Name     `xml:"testsuite"`
+		Name     string       `xml:"name,attr"`
+		Tests    int          `xml:"tests,attr"`
+		Failures int          `xml:"failures,attr"`
+		Skipped  int          `xml:"skipped,attr"`
+		Time     string       `xml:"time,attr"`
+		Cases    []*junitCase `xml:"testcase"`
+		elapsed  time.Duration
+	}
+
+	junitCase struct {
+		XMLName   xml.Name      `xml:"testcase"`
+		ClassName string        `xml:"classname,attr"`
+		Name      string        `xml:"name,attr"`
+		File      string        `xml:"file,attr,omitempty"`
+		Line      int           `xml:"line,attr,omitempty"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	}
+
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Text    string `xml:",chardata"`
+	}
+
+	junitSkipped struct {
+		Message string `xml:"message,attr"`
+	}
+)
+
+// NewJUnitReporter returns a Reporter that writes a JUnit XML report to the given path when
+// the test run is done. Each top level group becomes a test suite and each example a test case
+// that is classified by the names of its enclosing groups. Top level examples are collected in
+// a suite that is named after the file that declares them.
+func NewJUnitReporter(path string) Reporter {
+	return &junitReporter{path: path, suites: make([]*junitSuite, 0)}
+}
+
+func (r *junitReporter) GroupStarted(path []string, group *TestGroup) {
+}
+
+func (r *junitReporter) GroupFinished(path []string, group *TestGroup, elapsed time.Duration) {
+	if len(path) == 0 {
+		r.lock.Lock()
+		r.suite(group.Name()).elapsed = elapsed
+		r.lock.Unlock()
+	}
+}
+
+// GroupFailed adds a failed test case that is named after the hooks of the group
+func (r *junitReporter) GroupFailed(path []string, group *TestGroup, message string) {
+	c := &junitCase{Name: hookNames[afterAll], Time: junitTime(0), Failure: junitFailureOf(message)}
+	c.ClassName = strings.Join(childPath(path, group.Name()), `/`)
+	if loc := group.Node().Location(); loc != nil {
+		c.File = loc.File()
+		c.Line = loc.Line()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	name := group.Name()
+	if len(path) > 0 {
+		name = path[0]
+	}
+	s := r.suite(name)
+	s.Tests++
+	s.Failures++
+	s.Cases = append(s.Cases, c)
+}
+
+func (r *junitReporter) ExampleStarted(path []string, example *TestExecutable) {
+}
+
+func (r *junitReporter) ExamplePassed(path []string, example *TestExecutable, elapsed time.Duration) {
+	r.addCase(path, example, elapsed, &junitCase{})
+}
+
+func (r *junitReporter) ExampleFailed(path []string, example *TestExecutable, elapsed time.Duration, message string) {
+	r.addCase(path, example, elapsed, &junitCase{Failure: junitFailureOf(message)})
+}
+
+func (r *junitReporter) ExampleSkipped(path []string, example *TestExecutable, reason string) {
+	r.addCase(path, example, 0, &junitCase{Skipped: &junitSkipped{Message: reason}})
+}
+
+func (r *junitReporter) ExamplePending(path []string, example *TestExecutable, elapsed time.Duration, reason string) {
+	r.addCase(path, example, elapsed, &junitCase{Skipped: &junitSkipped{Message: `pending: ` + reason}})
+}
+
+func (r *junitReporter) TearDownFailed(path []string, example *TestExecutable, err error) {
+}
+
+// addCase adds the given test case to the suite of the example after completing it with the
+// properties of the example
+func (r *junitReporter) addCase(path []string, example *TestExecutable, elapsed time.Duration, c *junitCase) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var s *junitSuite
+	if len(path) == 0 {
+		s = r.suite(fileOf(example))
+		s.elapsed += elapsed
+	} else {
+		s = r.suite(path[0])
+	}
+	s.Tests++
+	if c.Failure != nil {
+		s.Failures++
+	}
+	if c.Skipped != nil {
+		s.Skipped++
+	}
+	c.ClassName = strings.Join(path, `/`)
+	c.Name = example.Name()
+	c.Time = junitTime(elapsed)
+	if loc := example.Node().Location(); loc != nil {
+		c.File = loc.File()
+		c.Line = loc.Line()
+	}
+	s.Cases = append(s.Cases, c)
+}
+
+func (r *junitReporter) suite(name string) *junitSuite {
+	for _, s := range r.suites {
+		if s.Name == name {
+			return s
+		}
+	}
+	s := &junitSuite{Name: name, Cases: make([]*junitCase, 0)}
+	r.suites = append(r.suites, s)
+	return s
+}
+
+func (r *junitReporter) Done() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	all := &junitSuites{Suites: r.suites}
+	total := time.Duration(0)
+	for _, s := range r.suites {
+		s.Time = junitTime(s.elapsed)
+		all.Tests += s.Tests
+		all.Failures += s.Failures
+		all.Skipped += s.Skipped
+		total += s.elapsed
+	}
+	all.Time = junitTime(total)
+
+	f, err := os.Create(r.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(f)
+	e.Indent(``, `  `)
+	return e.Encode(all)
+}
+
+// fileOf returns the file that declares the given example, or the name of the example when its location
+// is unknown
+func fileOf(example *TestExecutable) string {
+	if loc := example.Node().Location(); loc != nil {
+		return loc.File()
+	}
+	return example.Name()
+}
+
+// junitFailureOf returns a failure with the given message as its text and the first line of the message
+// as its summary
+func junitFailureOf(message string) *junitFailure {
+	summary := message
+	if nl := strings.IndexByte(summary, '\n'); nl >= 0 {
+		summary = summary[:nl]
+	}
+	return &junitFailure{Message: summary, Text: message}
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf(`%.3f`, d.Seconds())
+}
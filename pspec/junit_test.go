@@ -0,0 +1,76 @@
+package pspec
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJUnitReport(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, `junit.xml`)
+
+	pass := testExample(`passes`, `a.pspec`, 1)
+	fail := testExample(`fails`, `a.pspec`, 3)
+	skip := testExample(`is skipped`, `a.pspec`, 5)
+	inner := testGroup(`inner`, `a.pspec`, 4, skip)
+	outer := testGroup(`outer`, `a.pspec`, 2, fail, inner)
+
+	r := NewJUnitReporter(path)
+	r.ExamplePassed([]string{}, pass, time.Second)
+	r.GroupStarted([]string{}, outer)
+	r.ExampleFailed([]string{`outer`}, fail, time.Second, "expected 1, got 2\ndetails")
+	r.GroupStarted([]string{`outer`}, inner)
+	r.ExampleSkipped([]string{`outer`, `inner`}, skip, `not now`)
+	r.GroupFinished([]string{`outer`}, inner, time.Second)
+	r.GroupFinished([]string{}, outer, 2*time.Second)
+	if err = r.Done(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := &junitSuites{}
+	if err = xml.Unmarshal(content, all); err != nil {
+		t.Fatal(err)
+	}
+	if all.Tests != 3 || all.Failures != 1 || all.Skipped != 1 {
+		t.Errorf(`expected 3 tests, 1 failure, and 1 skipped, got %d, %d, and %d`, all.Tests, all.Failures, all.Skipped)
+	}
+	if len(all.Suites) != 2 {
+		t.Fatalf(`expected 2 suites, got %d`, len(all.Suites))
+	}
+
+	file := all.Suites[0]
+	if file.Name != `a.pspec` || len(file.Cases) != 1 {
+		t.Fatalf(`expected suite a.pspec with 1 case, got %s with %d cases`, file.Name, len(file.Cases))
+	}
+	if c := file.Cases[0]; c.Name != `passes` || c.ClassName != `` || c.Failure != nil || c.Skipped != nil {
+		t.Errorf(`unexpected passed case %+v`, c)
+	}
+
+	group := all.Suites[1]
+	if group.Name != `outer` || group.Tests != 2 || group.Failures != 1 || group.Skipped != 1 {
+		t.Fatalf(`unexpected group suite %+v`, group)
+	}
+	c := group.Cases[0]
+	if c.Name != `fails` || c.ClassName != `outer` || c.File != `a.pspec` || c.Line != 3 {
+		t.Errorf(`unexpected failed case %+v`, c)
+	}
+	if c.Failure == nil || c.Failure.Message != `expected 1, got 2` || c.Failure.Text != "expected 1, got 2\ndetails" {
+		t.Errorf(`unexpected failure %+v`, c.Failure)
+	}
+	c = group.Cases[1]
+	if c.Name != `is skipped` || c.ClassName != `outer/inner` || c.Skipped == nil || c.Skipped.Message != `not now` {
+		t.Errorf(`unexpected skipped case %+v`, c)
+	}
+}
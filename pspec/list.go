@@ -0,0 +1,58 @@
+package pspec
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ListTests writes the hierarchy of the given tests to the given writer without running them. Each test is
+// listed with its location and its tags. An example is also listed with the number of executables that
+// are created by combining each of its inputs with each of its results.
+func ListTests(out io.Writer, tests []Test) {
+	examples := listTests(out, tests, nil, ``)
+	fmt.Fprintf(out, "\n%d examples\n", examples)
+}
+
+func listTests(out io.Writer, tests []Test, parentContext *TestContext, indent string) int {
+	examples := 0
+	for _, test := range tests {
+		ctx := newTestContext(parentContext, test.Node())
+		fmt.Fprintf(out, "%s%s%s\n", indent, test.Name(), nodeDetails(test.Node()))
+		if g, ok := test.(*TestGroup); ok {
+			examples += listTests(out, g.Tests(), ctx, indent+`  `)
+			continue
+		}
+		examples++
+		if e, ok := test.Node().(*Example); ok {
+			inputs := e.collectInputs(ctx, make([]Input, 0, 8))
+			executables := 0
+			for _, input := range inputs {
+				executables += executableCount(input) * len(e.results)
+			}
+			fmt.Fprintf(out, "%s  %d inputs x %d results: %d executables\n", indent, len(inputs), len(e.results), executables)
+		}
+	}
+	return examples
+}
+
+// executableCount returns the number of executables that the given input creates for each result. The
+// executables are not created since creating them may evaluate the expected results.
+func executableCount(input Input) int {
+	if s, ok := input.(*Source); ok {
+		return len(s.sources)
+	}
+	return 1
+}
+
+// nodeDetails returns the location and the tags of the given node formatted for a listing
+func nodeDetails(n Node) string {
+	b := strings.Builder{}
+	if loc := n.Location(); loc != nil {
+		fmt.Fprintf(&b, ` (%s:%d)`, loc.File(), loc.Line())
+	}
+	if tags := n.Tags(); len(tags) > 0 {
+		fmt.Fprintf(&b, ` [%s]`, strings.Join(tags, `, `))
+	}
+	return b.String()
+}
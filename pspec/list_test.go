@@ -0,0 +1,20 @@
+package pspec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDryRunDoesNotCreateExecutables(t *testing.T) {
+	out, ok := runSpec(t, `
+Example('malformed expectation',
+  Given(Source('1', '2')),
+  Parses_to('(invalid'))
+`, DryRun())
+	if !ok {
+		t.Fatalf("expected the dry run to succeed, got:\n%s", out)
+	}
+	if !strings.Contains(out, `1 inputs x 1 results: 2 executables`) || !strings.Contains(out, "\n1 examples\n") {
+		t.Errorf("expected the example to be listed, got:\n%s", out)
+	}
+}
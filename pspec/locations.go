@@ -0,0 +1,289 @@
+package pspec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// LocationsResult is the result of a Parses_with_locations expectation. It asserts the line, pos, and
+	// length of subexpressions that are appointed by PN patterns or PN paths. The expected location is
+	// either given explicitly or by a marker in the source.
+	LocationsResult struct {
+		location issue.Location
+		example  *Example
+		expected []*expectedLocation
+	}
+
+	// expectedLocation is the expected location of the expression appointed by a key. A key that starts
+	// with '/' is a PN path and all other keys are PN patterns. A zero line, pos, or length is not checked.
+	expectedLocation struct {
+		pattern string
+		path    []string
+		marker  string
+		line    int
+		pos     int
+		length  int
+	}
+
+	// pnExpressions maps the nodes of the PN tree of an expression to the subexpressions that produce them.
+	// Nodes that are not produced by an expression, such as the entries of the map of a call, are not mapped.
+	pnExpressions struct {
+		root        *pnNode
+		expressions map[*pnNode]parser.Expression
+	}
+)
+
+func (l *LocationsResult) CreateTest(actual interface{}) Executable {
+	path, source, epp := pathContentAndEpp(actual)
+	patterns := make([]*pnNode, len(l.expected))
+	for i, e := range l.expected {
+		if e.path == nil {
+			patterns[i] = parsePNPattern(l.location, e.pattern)
+		}
+	}
+
+	return func(context *TestContext, assertions Assertions) {
+		o := context.ParserOptions()
+		if epp {
+			o = append(o, parser.EppMode)
+		}
+		src, markers, err := markedSource(context.resolveLazyValue(source).String(), l.usesMarkers())
+		if err != nil {
+			assertions.Fail(err.Error())
+		}
+		actual, issues := parseAndValidate(path, src, false, o...)
+		failOnError(assertions, issues)
+
+		pe := newPNExpressions(stripBlock(actual))
+		failures := make([]string, 0)
+		for i, e := range l.expected {
+			if e.marker != `` {
+				m, ok := markers[e.marker]
+				if !ok {
+					failures = append(failures, fmt.Sprintf(`expression at %s: no marker labeled '%s'`, e.pattern, e.marker))
+					continue
+				}
+				e = &expectedLocation{e.pattern, e.path, e.marker, m.line, m.pos, m.length}
+			}
+			var found parser.Expression
+			if e.path != nil {
+				found = pe.at(e.path)
+			} else {
+				found = pe.find(patterns[i], e)
+			}
+			if found == nil {
+				failures = append(failures, fmt.Sprintf(`no expression at %s%s`, e.pattern, e.startDescription()))
+				continue
+			}
+			if !e.matches(found) {
+				failures = append(failures, fmt.Sprintf(`expression at %s: expected %s, got line %d, pos %d, length %d`,
+					e.pattern, e, found.Line(), found.Pos(), found.ByteLength()))
+			}
+		}
+		if len(failures) > 0 {
+			assertions.Fail(strings.Join(failures, "\n"))
+		}
+	}
+}
+
+// usesMarkers returns true if an expected location is given by a marker
+func (l *LocationsResult) usesMarkers() bool {
+	for _, e := range l.expected {
+		if e.marker != `` {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LocationsResult) setExample(example *Example) {
+	l.example = example
+}
+
+// newPNExpressions converts the given expression to PN once and maps the nodes of the resulting tree to the
+// subexpressions that produce them. The direct subexpressions of an expression are found among the nodes
+// of its tree, in preorder, by comparing their PN.
+func newPNExpressions(expr parser.Expression) *pnExpressions {
+	children := make(map[parser.Expression][]parser.Expression)
+	expr.AllContents([]parser.Expression{}, func(path []parser.Expression, e parser.Expression) {
+		parent := expr
+		if len(path) > 0 {
+			parent = path[len(path)-1]
+		}
+		children[parent] = append(children[parent], e)
+	})
+
+	pe := &pnExpressions{root: pnNodeOf(expr.ToPN()), expressions: make(map[*pnNode]parser.Expression)}
+	texts := make(map[*pnNode]string)
+	var assign func(e parser.Expression, n *pnNode)
+	assign = func(e parser.Expression, n *pnNode) {
+		pe.expressions[n] = e
+		descendants := make([]*pnNode, 0)
+		for _, c := range n.elements {
+			c.each(func(d *pnNode) bool {
+				descendants = append(descendants, d)
+				return true
+			})
+		}
+
+		// A node that belongs to the tree of a sibling that is already found is never considered again
+		claimed := make(map[*pnNode]bool)
+		for _, c := range children[e] {
+			text := c.ToPN().String()
+			for _, d := range descendants {
+				if claimed[d] {
+					continue
+				}
+				if _, ok := texts[d]; !ok {
+					texts[d] = d.String()
+				}
+				if texts[d] == text {
+					assign(c, d)
+					d.each(func(cd *pnNode) bool {
+						claimed[cd] = true
+						return true
+					})
+					break
+				}
+			}
+		}
+	}
+	assign(expr, pe.root)
+	return pe
+}
+
+// find returns the expression of the first node in preorder that matches the given pattern and starts
+// at the line and pos of the given marker location, or the first matching node when no marker is given
+func (pe *pnExpressions) find(pattern *pnNode, e *expectedLocation) parser.Expression {
+	var found parser.Expression
+	pe.root.each(func(n *pnNode) bool {
+		if x, ok := pe.expressions[n]; ok && (e.marker == `` || x.Line() == e.line && x.Pos() == e.pos) && pattern.matches(n) {
+			found = x
+		}
+		return found == nil
+	})
+	return found
+}
+
+// at returns the expression of the node that is appointed by the given PN path, or nil when no such node
+// exists or when it's not produced by an expression. The segments of the path are indexes of the elements
+// of lists and calls and keys of maps.
+func (pe *pnExpressions) at(path []string) parser.Expression {
+	n := pe.root
+	for _, segment := range path {
+		var next *pnNode
+		if n.kind == tokenLc {
+			for _, e := range n.elements {
+				if e.key == segment {
+					next = e
+					break
+				}
+			}
+		} else if i, err := strconv.Atoi(segment); err == nil && n.isContainer() && i >= 0 && i < len(n.elements) {
+			next = n.elements[i]
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return pe.expressions[n]
+}
+
+// each calls the given function with this node and, in preorder, with all of its descendants until the
+// function returns false
+func (n *pnNode) each(f func(*pnNode) bool) bool {
+	if !f(n) {
+		return false
+	}
+	for _, e := range n.elements {
+		if !e.each(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// startDescription describes where the expression is expected to start when that is given by a marker
+func (e *expectedLocation) startDescription() string {
+	if e.marker == `` {
+		return ``
+	}
+	return fmt.Sprintf(` starting at marker '%s' (line %d, pos %d)`, e.marker, e.line, e.pos)
+}
+
+func (e *expectedLocation) matches(expr parser.Expression) bool {
+	return (e.line == 0 || e.line == expr.Line()) &&
+		(e.pos == 0 || e.pos == expr.Pos()) &&
+		(e.length == 0 || e.length == expr.ByteLength())
+}
+
+func (e *expectedLocation) String() string {
+	parts := make([]string, 0, 3)
+	if e.line != 0 {
+		parts = append(parts, fmt.Sprintf(`line %d`, e.line))
+	}
+	if e.pos != 0 {
+		parts = append(parts, fmt.Sprintf(`pos %d`, e.pos))
+	}
+	if e.length != 0 {
+		parts = append(parts, fmt.Sprintf(`length %d`, e.length))
+	}
+	return strings.Join(parts, `, `)
+}
+
+// makeExpectedLocation creates the expected location for the given PN pattern or PN path from a [line, pos, length]
+// tuple, a hash with one or more of the keys 'line', 'pos', and 'length', or a marker label
+func makeExpectedLocation(key string, v px.Value) *expectedLocation {
+	e := &expectedLocation{pattern: key}
+	if strings.HasPrefix(key, `/`) {
+		e.path = make([]string, 0)
+		for _, segment := range strings.Split(key[1:], `/`) {
+			if segment != `` {
+				e.path = append(e.path, segment)
+			}
+		}
+	}
+	switch v := v.(type) {
+	case px.StringValue:
+		e.marker = v.String()
+	case px.OrderedMap:
+		v.EachPair(func(k, iv px.Value) {
+			n := int(iv.(px.Number).Int())
+			switch k.String() {
+			case `line`:
+				e.line = n
+			case `pos`:
+				e.pos = n
+			default:
+				e.length = n
+			}
+		})
+	case px.List:
+		e.line = int(v.At(0).(px.Number).Int())
+		e.pos = int(v.At(1).(px.Number).Int())
+		e.length = int(v.At(2).(px.Number).Int())
+	}
+	return e
+}
+
+func init() {
+	px.NewGoConstructor(`PSpec::Parses_with_locations`,
+		func(d px.Dispatch) {
+			d.Param(`Hash[String[1],Variant[Tuple[Integer[1],Integer[1],Integer[1]],Hash[Enum['line','pos','length'],Integer[1]],String[1]]]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				expected := make([]*expectedLocation, 0)
+				args[0].(*types.Hash).EachPair(func(k, v px.Value) {
+					expected = append(expected, makeExpectedLocation(k.String(), v))
+				})
+				return types.WrapRuntime(&LocationsResult{location: c.StackTop(), expected: expected})
+			})
+		})
+}
@@ -0,0 +1,54 @@
+package pspec
+
+import (
+	"testing"
+)
+
+func testPNExpressions(t *testing.T, source string) *pnExpressions {
+	t.Helper()
+	expr, issues := parseAndValidate(``, source, false)
+	if hasError(issues) {
+		t.Fatal(issues[0].Error())
+	}
+	return newPNExpressions(stripBlock(expr))
+}
+
+func TestPNPathAppointsExpression(t *testing.T) {
+	pe := testPNExpressions(t, `[$a, 22 + 1]`)
+	for _, tc := range []struct {
+		path   []string
+		pos    int
+		length int
+	}{
+		{[]string{}, 1, 12},
+		{[]string{`0`}, 2, 2},
+		{[]string{`1`}, 6, 6},
+		{[]string{`1`, `1`}, 11, 1},
+	} {
+		e := pe.at(tc.path)
+		if e == nil {
+			t.Errorf(`no expression at %v`, tc.path)
+		} else if e.Pos() != tc.pos || e.ByteLength() != tc.length {
+			t.Errorf(`expected pos %d, length %d at %v, got pos %d, length %d`, tc.pos, tc.length, tc.path, e.Pos(), e.ByteLength())
+		}
+	}
+	for _, path := range [][]string{{`2`}, {`0`, `0`}, {`x`}} {
+		if e := pe.at(path); e != nil {
+			t.Errorf(`expected no expression at %v, got %s`, path, e.ToPN())
+		}
+	}
+}
+
+func TestMarkerSelectsExpressionStartingAtMarker(t *testing.T) {
+	pe := testPNExpressions(t, `[$x, $x]`)
+	pattern := parsePNPattern(pnTestLocation, `(var "x")`)
+	if e := pe.find(pattern, &expectedLocation{}); e == nil || e.Pos() != 2 {
+		t.Errorf(`expected the first match at pos 2, got %v`, e)
+	}
+	if e := pe.find(pattern, &expectedLocation{marker: `x`, line: 1, pos: 6}); e == nil || e.Pos() != 6 {
+		t.Errorf(`expected the match at the marker at pos 6, got %v`, e)
+	}
+	if e := pe.find(pattern, &expectedLocation{marker: `x`, line: 1, pos: 3}); e != nil {
+		t.Errorf(`expected no match at pos 3, got %s`, e.ToPN())
+	}
+}
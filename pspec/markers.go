@@ -0,0 +1,62 @@
+package pspec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// sourceMarker is the location of a marker in a source. A marker is a line that consists of whitespace
+// followed by one or more '^' characters and an optional label. It marks the text above the '^'
+// characters on the closest preceding line that is not a marker.
+type sourceMarker struct {
+	label  string
+	line   int
+	pos    int
+	length int
+}
+
+var markerLine = regexp.MustCompile(`\A([ \t]*)(\^+)(?:[ \t]+(\S.*?))?[ \t]*\z`)
+
+// markedSource returns the source to parse and its markers. The marker lines are stripped from the source
+// only when markers are used by the expectations of the source, so that a line that only happens to look
+// like a marker is kept when no expectation refers to a marker.
+func markedSource(source string, used bool) (string, map[string]*sourceMarker, error) {
+	if !used {
+		return source, nil, nil
+	}
+	return stripMarkers(source)
+}
+
+// stripMarkers removes all marker lines from the given source and returns the resulting source together
+// with the markers, keyed by label. The line and pos of a marker are 1-based and refer to the returned
+// source. A marker without a label is labeled with the text that it marks. An error is returned when two
+// markers have the same label.
+func stripMarkers(source string) (string, map[string]*sourceMarker, error) {
+	markers := make(map[string]*sourceMarker)
+	lines := strings.Split(source, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		m := markerLine.FindStringSubmatch(line)
+		if m == nil || len(kept) == 0 {
+			kept = append(kept, line)
+			continue
+		}
+		marked := kept[len(kept)-1]
+		pos := len(m[1])
+		length := len(m[2])
+		label := m[3]
+		if label == `` && pos < len(marked) {
+			end := pos + length
+			if end > len(marked) {
+				end = len(marked)
+			}
+			label = marked[pos:end]
+		}
+		if _, ok := markers[label]; ok {
+			return ``, nil, fmt.Errorf(`more than one marker is labeled '%s'`, label)
+		}
+		markers[label] = &sourceMarker{label: label, line: len(kept), pos: pos + 1, length: length}
+	}
+	return strings.Join(kept, "\n"), markers, nil
+}
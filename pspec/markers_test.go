@@ -0,0 +1,25 @@
+package pspec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkedSourceIsStrippedOnlyWhenMarkersAreUsed(t *testing.T) {
+	source := "[1, 22]\n    ^^ n"
+	src, markers, err := markedSource(source, false)
+	if err != nil || src != source || len(markers) != 0 {
+		t.Errorf("expected the source to be kept, got %q, %d markers, and error %v", src, len(markers), err)
+	}
+	src, markers, err = markedSource(source, true)
+	if err != nil || src != `[1, 22]` || markers[`n`] == nil || markers[`n`].pos != 5 {
+		t.Errorf("expected the marker to be stripped, got %q, %d markers, and error %v", src, len(markers), err)
+	}
+}
+
+func TestDuplicateMarkerLabelIsAnError(t *testing.T) {
+	_, _, err := stripMarkers("[1, 22]\n ^ n\n    ^^ n")
+	if err == nil || !strings.Contains(err.Error(), `more than one marker is labeled 'n'`) {
+		t.Errorf("expected a duplicate label error, got %v", err)
+	}
+}
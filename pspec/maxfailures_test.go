@@ -0,0 +1,60 @@
+package pspec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// budgetTests returns a group with two failing examples followed by a skipped example and a nested group
+// with an example that passes
+func budgetTests() []Test {
+	fail := func(context *TestContext, assertions Assertions) { assertions.Fail(`boom`) }
+	pass := func(context *TestContext, assertions Assertions) {}
+	first := testExample(`first`, `test.pspec`, 3)
+	first.test = fail
+	second := testExample(`second`, `test.pspec`, 4)
+	second.test = fail
+	skipped := testExample(`skipped`, `test.pspec`, 5)
+	skipped.test = pass
+	skipped.Node().(*Example).addOptions([]NodeOption{&Skip{`later`}})
+	third := testExample(`third`, `test.pspec`, 7)
+	third.test = pass
+	return []Test{testGroup(`budget`, `test.pspec`, 2, first, second, skipped, testGroup(`nested`, `test.pspec`, 6, third))}
+}
+
+func TestMaxFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		opts    []Option
+		summary string
+		notRun  string
+	}{
+		{`fail fast`, []Option{FailFast()},
+			`4 examples, 1 failures, 1 skipped, 2 not run`,
+			`2 examples were not run because the maximum of 1 failures was reached`},
+		{`max failures`, []Option{MaxFailures(2)},
+			`4 examples, 2 failures, 1 skipped, 1 not run`,
+			`1 examples were not run because the maximum of 2 failures was reached`},
+		{`unlimited`, nil,
+			`4 examples, 2 failures, 1 skipped`,
+			``},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out := bytes.NewBufferString(``)
+			if NewRunner(out, false, tc.opts...).Run(budgetTests()) {
+				t.Fatalf("expected the run to fail, got:\n%s", out)
+			}
+			if !strings.Contains(out.String(), tc.summary+"\n") {
+				t.Errorf("expected %q, got:\n%s", tc.summary, out)
+			}
+			if tc.notRun == `` {
+				if strings.Contains(out.String(), `were not run`) {
+					t.Errorf("expected all examples to run, got:\n%s", out)
+				}
+			} else if !strings.Contains(out.String(), tc.notRun) {
+				t.Errorf("expected %q, got:\n%s", tc.notRun, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package pspec
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Option configures a test run started by RunPspecTests or a Runner
+	Option func(o *options)
+
+	options struct {
+		reporters reporters
+		filter    *regexp.Regexp
+		lines     []fileLine
+		includes  []string
+		excludes  []string
+		parallel  int
+		timeout   time.Duration
+		maxFailed int32
+		failed    int32
+		notRun    int32
+		blocked   int32
+		dryRun    bool
+		golden    *goldenUpdates
+		cache     string
+		onlyFail  bool
+		noFailed  bool
+		failures  map[string]bool
+		shuffle   bool
+		seed      int64
+		slots     chan struct{}
+		err       error
+	}
+)
+
+// WithReporter adds a reporter that will be notified about the progress of the test run
+func WithReporter(r Reporter) Option {
+	return func(o *options) {
+		o.reporters = append(o.reporters, r)
+	}
+}
+
+// JUnitReport adds a reporter that writes a JUnit XML report to the given path when the
+// test run is done.
+func JUnitReport(path string) Option {
+	// The reporter is created when the option is applied so that each run gets a reporter of its own
+	return func(o *options) {
+		WithReporter(NewJUnitReporter(path))(o)
+	}
+}
+
+// JSONReport adds a reporter that writes a stream of newline delimited JSON events to the
+// given path. A path of "-" denotes stdout.
+func JSONReport(path string) Option {
+	return func(o *options) {
+		WithReporter(newJSONFileReporter(path))(o)
+	}
+}
+
+// Filter restricts the run to the examples whose full name matches the given pattern. The full
+// name of an example is its description preceded by the descriptions of its enclosing groups,
+// separated by spaces.
+func Filter(pattern *regexp.Regexp) Option {
+	return func(o *options) {
+		o.filter = pattern
+	}
+}
+
+// Line restricts the run to the Example or Examples that is declared on the given line of the
+// given file, or to the closest one declared above that line. Multiple Line options select
+// all the nodes that they appoint.
+func Line(file string, line int) Option {
+	return func(o *options) {
+		o.lines = append(o.lines, fileLine{file, line})
+	}
+}
+
+// IncludeTags restricts the run to examples that have at least one of the given tags. An example
+// has the tags that were declared for it and for its enclosing groups.
+func IncludeTags(tags ...string) Option {
+	return func(o *options) {
+		o.includes = append(o.includes, tags...)
+	}
+}
+
+// ExcludeTags excludes examples that have any of the given tags from the run
+func ExcludeTags(tags ...string) Option {
+	return func(o *options) {
+		o.excludes = append(o.excludes, tags...)
+	}
+}
+
+// Parallel allows up to n examples to run concurrently. Examples that change pcore settings, that share a
+// scope or settings established by a Before_all hook, or that are declared Serial, never run concurrently
+// with other examples. When tests run using a testing.T, the -test.parallel flag also limits the number
+// of concurrent examples.
+func Parallel(n int) Option {
+	return func(o *options) {
+		o.parallel = n
+	}
+}
+
+// DefaultTimeout sets the maximum time that an example may run unless it declares a Timeout of its own.
+// An example that times out is reported as failed and the run continues with the next example.
+func DefaultTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// MaxFailures stops the run when the given number of examples have failed. The examples that remain are
+// not run and are counted as such in the summary, although reporters are told that they were skipped.
+// Examples that are skipped for other reasons are still counted as skipped.
+func MaxFailures(n int) Option {
+	return func(o *options) {
+		o.maxFailed = int32(n)
+	}
+}
+
+// FailFast stops the run when the first example fails
+func FailFast() Option {
+	return MaxFailures(1)
+}
+
+// FailureCache records the failed examples of the run in the file at the given path
+func FailureCache(path string) Option {
+	return func(o *options) {
+		o.cache = path
+	}
+}
+
+// OnlyFailures restricts the run to the examples that failed in the previous run. The failed examples are
+// read from the file given by the FailureCache option, or from DefaultFailureCache. The file is updated
+// when the run is done. All examples are run when the file is missing or records no failed examples.
+func OnlyFailures() Option {
+	return func(o *options) {
+		o.onlyFail = true
+	}
+}
+
+// Update rewrites the expected values of failing Parses_to and Evaluates_to expectations in the spec
+// files with the actual values, and creates or replaces snapshots that are missing or don't match, instead
+// of failing the examples
+func Update() Option {
+	return func(o *options) {
+		if o.golden == nil {
+			o.golden = newGoldenUpdates()
+		}
+	}
+}
+
+// DryRun lists the selected tests instead of running them
+func DryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// Shuffle runs the examples in random order. The order of the siblings of each group is permuted using a
+// seed that is based on the current time. The seed is printed so that the order can be reproduced using
+// the Seed option.
+func Shuffle() Option {
+	return func(o *options) {
+		if !o.shuffle {
+			o.shuffle = true
+			o.seed = time.Now().UnixNano()
+		}
+	}
+}
+
+// Seed runs the examples in the random order that is determined by the given seed
+func Seed(seed int64) Option {
+	return func(o *options) {
+		o.shuffle = true
+		o.seed = seed
+	}
+}
+
+// TagSelection parses a comma separated list of tags and returns options that include those tags
+// and exclude those that are prefixed with '~'.
+func TagSelection(s string) []Option {
+	opts := make([]Option, 0)
+	for _, tag := range strings.Split(s, `,`) {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, `~`) {
+			opts = append(opts, ExcludeTags(tag[1:]))
+		} else if tag != `` {
+			opts = append(opts, IncludeTags(tag))
+		}
+	}
+	return opts
+}
+
+// Lines parses a comma separated list of <file>:<line> entries and returns a Line option for each entry
+func Lines(s string) ([]Option, error) {
+	fls, err := parseFileLines(s)
+	if err != nil {
+		return nil, err
+	}
+	opts := make([]Option, len(fls))
+	for i, fl := range fls {
+		opts[i] = Line(fl.file, fl.line)
+	}
+	return opts, nil
+}
+
+// newOptions creates the options from the environment and then applies the given options.
+// Recognized environment variables are:
+//
+//	PSPEC_JUNIT          path of a JUnit XML report to write
+//	PSPEC_JSON           path of a file that will receive a stream of JSON events, or "-" for stdout
+//	PSPEC_FILTER         regular expression that the full name of an example must match
+//	PSPEC_LINE           comma separated list of <file>:<line> that appoint the nodes to run
+//	PSPEC_TAGS           comma separated list of tags to include, or to exclude when prefixed with '~'
+//	PSPEC_PARALLEL       maximum number of examples that run concurrently
+//	PSPEC_TIMEOUT        default timeout of examples, such as "30s" or "2m"
+//	PSPEC_MAX_FAILURES   number of failed examples that stops the run
+//	PSPEC_FAIL_FAST      stop the run when the first example fails when set to "true"
+//	PSPEC_FAILURE_CACHE  path of a file that records the failed examples of the run
+//	PSPEC_ONLY_FAILURES  run only the examples that failed in the previous run when set to "true"
+//	PSPEC_UPDATE         rewrite failing expectations and write missing snapshots when set to "true"
+//	PSPEC_DRY_RUN        list the selected tests instead of running them when set to "true"
+//	PSPEC_SHUFFLE        run the examples in random order when set to "true"
+//	PSPEC_SEED           run the examples in the random order determined by the given seed
+func newOptions(opts []Option) *options {
+	o := &options{reporters: make(reporters, 0)}
+	if path := os.Getenv(`PSPEC_JUNIT`); path != `` {
+		JUnitReport(path)(o)
+	}
+	if path := os.Getenv(`PSPEC_JSON`); path != `` {
+		JSONReport(path)(o)
+	}
+	if filter := os.Getenv(`PSPEC_FILTER`); filter != `` {
+		if rx, err := regexp.Compile(filter); err == nil {
+			Filter(rx)(o)
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_FILTER: %s`, err.Error()))
+		}
+	}
+	if lines := os.Getenv(`PSPEC_LINE`); lines != `` {
+		if fls, err := parseFileLines(lines); err == nil {
+			o.lines = append(o.lines, fls...)
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_LINE: %s`, err.Error()))
+		}
+	}
+	if tags := os.Getenv(`PSPEC_TAGS`); tags != `` {
+		for _, opt := range TagSelection(tags) {
+			opt(o)
+		}
+	}
+	if parallel := os.Getenv(`PSPEC_PARALLEL`); parallel != `` {
+		if n, err := strconv.Atoi(parallel); err == nil {
+			Parallel(n)(o)
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_PARALLEL: %s`, err.Error()))
+		}
+	}
+	if timeout := os.Getenv(`PSPEC_TIMEOUT`); timeout != `` {
+		if d, err := time.ParseDuration(timeout); err == nil {
+			DefaultTimeout(d)(o)
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_TIMEOUT: %s`, err.Error()))
+		}
+	}
+	if maxFailures := os.Getenv(`PSPEC_MAX_FAILURES`); maxFailures != `` {
+		if n, err := strconv.Atoi(maxFailures); err == nil {
+			MaxFailures(n)(o)
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_MAX_FAILURES: %s`, err.Error()))
+		}
+	}
+	if failFast := os.Getenv(`PSPEC_FAIL_FAST`); failFast != `` {
+		if b, err := strconv.ParseBool(failFast); err == nil {
+			if b {
+				FailFast()(o)
+			}
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_FAIL_FAST: %s`, err.Error()))
+		}
+	}
+	if path := os.Getenv(`PSPEC_FAILURE_CACHE`); path != `` {
+		FailureCache(path)(o)
+	}
+	if onlyFailures := os.Getenv(`PSPEC_ONLY_FAILURES`); onlyFailures != `` {
+		if b, err := strconv.ParseBool(onlyFailures); err == nil {
+			if b {
+				OnlyFailures()(o)
+			}
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_ONLY_FAILURES: %s`, err.Error()))
+		}
+	}
+	if update := os.Getenv(`PSPEC_UPDATE`); update != `` {
+		if b, err := strconv.ParseBool(update); err == nil {
+			if b {
+				Update()(o)
+			}
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_UPDATE: %s`, err.Error()))
+		}
+	}
+	if dryRun := os.Getenv(`PSPEC_DRY_RUN`); dryRun != `` {
+		if b, err := strconv.ParseBool(dryRun); err == nil {
+			if b {
+				DryRun()(o)
+			}
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_DRY_RUN: %s`, err.Error()))
+		}
+	}
+	if shuffle := os.Getenv(`PSPEC_SHUFFLE`); shuffle != `` {
+		if b, err := strconv.ParseBool(shuffle); err == nil {
+			if b {
+				Shuffle()(o)
+			}
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_SHUFFLE: %s`, err.Error()))
+		}
+	}
+	if seed := os.Getenv(`PSPEC_SEED`); seed != `` {
+		if n, err := strconv.ParseInt(seed, 10, 64); err == nil {
+			Seed(n)(o)
+		} else {
+			o.setError(fmt.Errorf(`PSPEC_SEED: %s`, err.Error()))
+		}
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.parallel > 1 {
+		o.slots = make(chan struct{}, o.parallel)
+	}
+	if o.onlyFail {
+		if o.cache == `` {
+			o.cache = DefaultFailureCache
+		}
+		failures, err := readFailures(o.cache)
+		if err != nil {
+			o.setError(err)
+		}
+		if len(failures) == 0 {
+			// Running nothing would report success although nothing is known about the examples
+			o.onlyFail = false
+			o.noFailed = true
+		}
+		o.failures = failures
+	}
+	if o.cache != `` && !o.dryRun {
+		WithReporter(newFailureRecorder(o.cache))(o)
+	}
+	return o
+}
+
+// concurrent returns true if the example of the given context may run concurrently with other examples
+func (o *options) concurrent(ctx *TestContext) bool {
+	return o.slots != nil && !ctx.serial()
+}
+
+// acquire blocks until fewer than the configured number of examples are running and returns the
+// function that releases the acquired slot
+func (o *options) acquire() func() {
+	o.slots <- struct{}{}
+	return func() { <-o.slots }
+}
+
+func (o *options) setError(err error) {
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+// selectTests returns the tests that remain after applying the filters of the options. Only focused tests
+// are selected when the tests contain focused tests. The selected tests are shuffled when requested. An
+// error is returned when a file line of the options doesn't appoint any test.
+func (o *options) selectTests(tests []Test) ([]Test, error) {
+	if hasFocus(tests) {
+		tests = filterByFocus(tests)
+	}
+	if len(o.lines) > 0 {
+		var err error
+		if tests, err = filterByLines(tests, o.lines); err != nil {
+			return nil, err
+		}
+	}
+	if o.onlyFail {
+		tests = filterByFailures(tests, o.failures)
+	}
+	if o.filter != nil {
+		tests = filterByName(tests, o.filter)
+	}
+	if len(o.includes) > 0 || len(o.excludes) > 0 {
+		tests = filterByTags(tests, o.includes, o.excludes)
+	}
+	if o.shuffle {
+		tests = shuffleTests(tests, rand.New(rand.NewSource(o.seed)))
+	}
+	return tests, nil
+}
+
+// recordFailure counts a failed example or group
+func (o *options) recordFailure() {
+	atomic.AddInt32(&o.failed, 1)
+}
+
+// exhausted returns true when the number of failures has reached the maximum number of failures
+func (o *options) exhausted() bool {
+	return o.maxFailed > 0 && atomic.LoadInt32(&o.failed) >= o.maxFailed
+}
+
+// notRunMessage returns the message that informs about the examples that were not run because the maximum
+// number of failures was reached or because they would have to wait for an example that timed out, or an
+// empty string when all examples were run
+func (o *options) notRunMessage() string {
+	msgs := make([]string, 0, 2)
+	if n := atomic.LoadInt32(&o.notRun); n > 0 {
+		msgs = append(msgs, fmt.Sprintf(`%d examples were not run because the maximum of %d failures was reached`, n, o.maxFailed))
+	}
+	if n := atomic.LoadInt32(&o.blocked); n > 0 {
+		msgs = append(msgs, fmt.Sprintf(`%d examples were not run because they would have to wait for an example that timed out`, n))
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// onlyFailuresMessage returns the message that informs that all examples are run since no failed examples
+// were recorded, or an empty string when that's not the case
+func (o *options) onlyFailuresMessage() string {
+	if !o.noFailed {
+		return ``
+	}
+	return fmt.Sprintf(`No failed examples are recorded in %s, running all examples`, o.cache)
+}
+
+// seedMessage returns the message that informs about the seed used when shuffling the tests, or an
+// empty string when the tests are not shuffled
+func (o *options) seedMessage() string {
+	if !o.shuffle {
+		return ``
+	}
+	return fmt.Sprintf(`Randomized with seed %d`, o.seed)
+}
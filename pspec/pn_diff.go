@@ -0,0 +1,77 @@
+package pspec
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pnDiff collects the subtrees that differ between two PN trees
+type pnDiff struct {
+	differences []string
+}
+
+// pnDiffMessage returns the failure message for an assertion that the actual PN tree matches the expected
+// tree. It contains the path to each differing subtree together with the expected and actual subtree.
+func pnDiffMessage(expected, actual *pnNode) string {
+	d := &pnDiff{make([]string, 0)}
+	d.diff(nil, expected, actual)
+
+	b := strings.Builder{}
+	b.WriteString(`parse result differs from the expected PN`)
+	for i, s := range d.differences {
+		if i == maxDifferences {
+			fmt.Fprintf(&b, "\n  ... and %d more differences", len(d.differences)-maxDifferences)
+			break
+		}
+		b.WriteString("\n")
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+func (d *pnDiff) diff(path []string, expected, actual *pnNode) {
+	if expected.matches(actual) {
+		return
+	}
+	if expected.isContainer() && expected.kind == actual.kind && expected.name == actual.name && expected.key == actual.key {
+		elements, rest := expected.patternElements()
+		if len(elements) == len(actual.elements) || rest && len(elements) < len(actual.elements) {
+			for i, e := range elements {
+				d.diff(append(path[:len(path):len(path)], expected.segment(i)), e, actual.elements[i])
+			}
+			return
+		}
+	}
+	where := `(top)`
+	if len(path) > 0 {
+		where = strings.Join(path, ` > `)
+	}
+	if expected.key == actual.key {
+		expected, actual = expected.withoutKey(), actual.withoutKey()
+	}
+	d.differences = append(d.differences, fmt.Sprintf("  at %s:\n    expected: %s\n    actual:   %s",
+		where, expected.prettyAt(14), actual.prettyAt(14)))
+}
+
+func (n *pnNode) isContainer() bool {
+	return n.kind == tokenLb || n.kind == tokenLc || n.kind == tokenLp
+}
+
+// segment returns the path segment that appoints the element at the given index of this node
+func (n *pnNode) segment(i int) string {
+	switch n.kind {
+	case tokenLc:
+		return `:` + n.elements[i].key
+	case tokenLp:
+		return fmt.Sprintf(`%s[%d]`, n.name, i)
+	default:
+		return fmt.Sprintf(`[%d]`, i)
+	}
+}
+
+// withoutKey returns a copy of this node that is not a map entry
+func (n *pnNode) withoutKey() *pnNode {
+	c := *n
+	c.key = ``
+	return &c
+}
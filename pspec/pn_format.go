@@ -0,0 +1,148 @@
+package pspec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-parser/parser"
+	"github.com/lyraproj/puppet-parser/pn"
+)
+
+// pnLineWidth is the width that a PN node may occupy on one line before its elements are broken up
+// into separate lines
+const pnLineWidth = 100
+
+// PrettyPN returns the given PN in canonical indented form. A list, map, or call that doesn't fit on one
+// line is written with one element per line.
+func PrettyPN(p pn.PN) string {
+	return pnNodeOf(p).pretty()
+}
+
+// pnNodeOf converts the given PN into a tree of pnNodes
+func pnNodeOf(p pn.PN) *pnNode {
+	return dataNode(p.ToData())
+}
+
+// dataNode converts the data produced by pn.PN.ToData into a pnNode. A call is represented by a map with
+// the key "^" and a list with the name followed by the arguments. A map is represented by a map with the
+// key "#" and a list with alternating keys and values.
+func dataNode(data interface{}) *pnNode {
+	switch d := data.(type) {
+	case []interface{}:
+		return &pnNode{kind: tokenLb, elements: dataNodes(d)}
+	case map[string]interface{}:
+		if c, ok := d[`^`].([]interface{}); ok && len(c) > 0 {
+			return &pnNode{kind: tokenLp, name: fmt.Sprint(c[0]), elements: dataNodes(c[1:])}
+		}
+		es, _ := d[`#`].([]interface{})
+		entries := make([]*pnNode, 0, len(es)/2)
+		for i := 0; i+1 < len(es); i += 2 {
+			e := dataNode(es[i+1])
+			e.key = fmt.Sprint(es[i])
+			entries = append(entries, e)
+		}
+		return &pnNode{kind: tokenLc, elements: entries}
+	case nil:
+		return &pnNode{kind: tokenNil}
+	case bool:
+		return &pnNode{kind: tokenBool, value: d}
+	case int:
+		return &pnNode{kind: tokenInt, value: int64(d)}
+	case int64:
+		return &pnNode{kind: tokenInt, value: d}
+	case float64:
+		return &pnNode{kind: tokenFloat, value: d}
+	default:
+		return &pnNode{kind: tokenString, value: fmt.Sprint(d)}
+	}
+}
+
+func dataNodes(data []interface{}) []*pnNode {
+	nodes := make([]*pnNode, len(data))
+	for i, d := range data {
+		nodes[i] = dataNode(d)
+	}
+	return nodes
+}
+
+// SourcePN parses and validates the given Puppet source and returns its PN in the form that is expected
+// by Parses_to, i.e. with a block that contains only one statement replaced by that statement.
+func SourcePN(name, source string, o ...parser.Option) (pn.PN, error) {
+	expr, issues := parseAndValidate(name, source, false, o...)
+	for _, i := range issues {
+		if i.Severity() == issue.SeverityError {
+			return nil, i
+		}
+	}
+	return stripBlock(expr).ToPN(), nil
+}
+
+// stripBlock returns the body of the given expression when it is a program and the only statement of that
+// body when it is a block that contains one statement
+func stripBlock(expr parser.Expression) parser.Expression {
+	if pr, ok := expr.(*parser.Program); ok {
+		expr = pr.Body()
+	}
+	if be, ok := expr.(*parser.BlockExpression); ok {
+		s := be.Statements()
+		if len(s) == 1 {
+			expr = s[0]
+		}
+	}
+	return expr
+}
+
+// pretty returns this node in canonical indented form
+func (n *pnNode) pretty() string {
+	return n.prettyAt(0)
+}
+
+// prettyAt returns this node in canonical indented form, assuming that it starts at the given column
+func (n *pnNode) prettyAt(column int) string {
+	b := bytes.NewBufferString(``)
+	n.format(b, column)
+	return b.String()
+}
+
+// format writes this node to the given buffer. The given column is the column at which the node starts.
+func (n *pnNode) format(b *bytes.Buffer, column int) {
+	if n.key != `` {
+		b.WriteString(`:`)
+		b.WriteString(n.key)
+		b.WriteByte(' ')
+		column += len(n.key) + 2
+	}
+	s := n.String()
+	if !n.isContainer() || len(n.elements) == 0 || column+len(s) <= pnLineWidth {
+		b.WriteString(s)
+		return
+	}
+
+	// Elements of a list or a map are aligned with the first element which is written directly after the
+	// opening bracket. Elements of a call are written on separate lines.
+	var end byte
+	elementIndent := column + 1
+	switch n.kind {
+	case tokenLb:
+		b.WriteByte('[')
+		end = ']'
+	case tokenLc:
+		b.WriteByte('{')
+		end = '}'
+	default:
+		b.WriteByte('(')
+		b.WriteString(n.name)
+		end = ')'
+		elementIndent = column + 2
+	}
+	for i, e := range n.elements {
+		if i > 0 || n.kind == tokenLp {
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(` `, elementIndent))
+		}
+		e.format(b, elementIndent)
+	}
+	b.WriteByte(end)
+}
@@ -13,8 +13,19 @@ import (
 type (
 	token rune
 
+	// pnNode is a node in the tree that results from parsing PN text. It is converted to a pn.PN but can
+	// also be compared with other nodes.
+	pnNode struct {
+		kind     token
+		key      string
+		name     string
+		value    interface{}
+		elements []*pnNode
+	}
+
 	pnParser struct {
 		location   issue.Location
+		wildcards  bool
 		text       string
 		pos        int
 		token      token
@@ -37,15 +48,38 @@ const (
 	tokenRc         = token('}')
 	tokenIdentifier = token('a')
 	tokenKey        = token(':')
+
+	// Wildcards are only recognized in patterns
+	tokenAny  = token('*')
+	tokenRest = token('~')
+)
+
+const (
+	wildcardAny  = `_*`
+	wildcardRest = `...`
 )
 
 func ParsePN(location issue.Location, content string) pn.PN {
+	return parsePN(location, content).toPN()
+}
+
+// parsePN parses the given content into a tree of pnNodes
+func parsePN(location issue.Location, content string) *pnNode {
 	p := &pnParser{location: location, text: content}
 	p.nextToken()
 	return p.parseNext()
 }
 
-func (p *pnParser) parseNext() pn.PN {
+// parsePNPattern parses the given content into a tree of pnNodes that may contain the wildcard "_*",
+// which matches any subtree, and the wildcard "...", which matches the remaining elements of a list
+// or a call.
+func parsePNPattern(location issue.Location, content string) *pnNode {
+	p := &pnParser{location: location, wildcards: true, text: content}
+	p.nextToken()
+	return p.parseNext()
+}
+
+func (p *pnParser) parseNext() *pnNode {
 	switch p.token {
 	case tokenLb:
 		return p.parseArray()
@@ -55,6 +89,12 @@ func (p *pnParser) parseNext() pn.PN {
 		return p.parseCall()
 	case tokenBool, tokenInt, tokenFloat, tokenString, tokenNil:
 		return p.parseLiteral()
+	case tokenIdentifier:
+		if p.wildcards && p.tokenValue == wildcardAny {
+			p.nextToken()
+			return &pnNode{kind: tokenAny}
+		}
+		panic(p.error(fmt.Sprintf(`unexpected '%v'`, p.tokenValue)))
 	case tokenEnd:
 		panic(p.error(`unexpected end of input`))
 	default:
@@ -62,19 +102,19 @@ func (p *pnParser) parseNext() pn.PN {
 	}
 }
 
-func (p *pnParser) parseLiteral() pn.PN {
-	pv := pn.Literal(p.tokenValue)
+func (p *pnParser) parseLiteral() *pnNode {
+	n := &pnNode{kind: p.token, value: p.tokenValue}
 	p.nextToken()
-	return pv
+	return n
 }
 
-func (p *pnParser) parseArray() pn.PN {
+func (p *pnParser) parseArray() *pnNode {
 	p.nextToken()
-	return pn.List(p.parseElements(tokenRb))
+	return &pnNode{kind: tokenLb, elements: p.parseElements(tokenRb)}
 }
 
-func (p *pnParser) parseMap() pn.PN {
-	entries := make([]pn.Entry, 0, 8)
+func (p *pnParser) parseMap() *pnNode {
+	entries := make([]*pnNode, 0, 8)
 	p.nextToken()
 	for p.token != tokenRc && p.token != tokenEnd {
 		if p.token != tokenKey {
@@ -82,28 +122,38 @@ func (p *pnParser) parseMap() pn.PN {
 		}
 		key := p.tokenValue.(string)
 		p.nextToken()
-		entries = append(entries, p.parseNext().WithName(key))
+		entry := p.parseNext()
+		entry.key = key
+		entries = append(entries, entry)
 	}
 	if p.token != tokenRc {
 		panic(p.error(`missing '}' to end map`))
 	}
 	p.nextToken()
-	return pn.Map(entries)
+	return &pnNode{kind: tokenLc, elements: entries}
 }
 
-func (p *pnParser) parseCall() pn.PN {
+func (p *pnParser) parseCall() *pnNode {
 	p.nextToken()
 	if p.token != tokenIdentifier {
 		panic(p.error(`expected identifier to follow '('`))
 	}
 	name := p.tokenValue.(string)
 	p.nextToken()
-	return pn.Call(name, p.parseElements(tokenRp)...)
+	return &pnNode{kind: tokenLp, name: name, elements: p.parseElements(tokenRp)}
 }
 
-func (p *pnParser) parseElements(endToken token) []pn.PN {
-	elements := make([]pn.PN, 0, 8)
+func (p *pnParser) parseElements(endToken token) []*pnNode {
+	elements := make([]*pnNode, 0, 8)
 	for p.token != endToken && p.token != tokenEnd {
+		if p.wildcards && p.token == tokenIdentifier && p.tokenValue == wildcardRest {
+			p.nextToken()
+			elements = append(elements, &pnNode{kind: tokenRest})
+			if p.token != endToken {
+				panic(p.error(fmt.Sprintf(`'%s' must be the last element of a list`, wildcardRest)))
+			}
+			break
+		}
 		elements = append(elements, p.parseNext())
 	}
 	if p.token != endToken {
@@ -113,6 +163,112 @@ func (p *pnParser) parseElements(endToken token) []pn.PN {
 	return elements
 }
 
+// toPN converts this node into a pn.PN
+func (n *pnNode) toPN() pn.PN {
+	switch n.kind {
+	case tokenLb:
+		return pn.List(n.elementPNs())
+	case tokenLc:
+		entries := make([]pn.Entry, len(n.elements))
+		for i, e := range n.elements {
+			entries[i] = e.toPN().WithName(e.key)
+		}
+		return pn.Map(entries)
+	case tokenLp:
+		return pn.Call(n.name, n.elementPNs()...)
+	default:
+		return pn.Literal(n.value)
+	}
+}
+
+func (n *pnNode) elementPNs() []pn.PN {
+	elements := make([]pn.PN, len(n.elements))
+	for i, e := range n.elements {
+		elements[i] = e.toPN()
+	}
+	return elements
+}
+
+// String returns the single line PN representation of this node
+func (n *pnNode) String() string {
+	b := bytes.NewBufferString(``)
+	n.writeTo(b)
+	return b.String()
+}
+
+func (n *pnNode) writeTo(b *bytes.Buffer) {
+	switch n.kind {
+	case tokenLb:
+		b.WriteByte('[')
+		n.writeElementsTo(b)
+		b.WriteByte(']')
+	case tokenLc:
+		b.WriteByte('{')
+		for i, e := range n.elements {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteByte(':')
+			b.WriteString(e.key)
+			b.WriteByte(' ')
+			e.writeTo(b)
+		}
+		b.WriteByte('}')
+	case tokenLp:
+		b.WriteByte('(')
+		b.WriteString(n.name)
+		if len(n.elements) > 0 {
+			b.WriteByte(' ')
+			n.writeElementsTo(b)
+		}
+		b.WriteByte(')')
+	case tokenAny:
+		b.WriteString(wildcardAny)
+	case tokenRest:
+		b.WriteString(wildcardRest)
+	default:
+		b.WriteString(pn.Literal(n.value).String())
+	}
+}
+
+func (n *pnNode) writeElementsTo(b *bytes.Buffer) {
+	for i, e := range n.elements {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		e.writeTo(b)
+	}
+}
+
+// matches returns true if the given node matches this node when this node is used as a pattern
+func (n *pnNode) matches(o *pnNode) bool {
+	if n.kind == tokenAny {
+		return n.key == o.key
+	}
+	if n.kind != o.kind || n.key != o.key || n.name != o.name || n.value != o.value {
+		return false
+	}
+	elements, rest := n.patternElements()
+	if len(elements) > len(o.elements) || !rest && len(elements) < len(o.elements) {
+		return false
+	}
+	for i, e := range elements {
+		if !e.matches(o.elements[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// patternElements returns the elements of this node that precede a "..." wildcard and true if such a
+// wildcard was found
+func (n *pnNode) patternElements() ([]*pnNode, bool) {
+	if last := len(n.elements) - 1; last >= 0 && n.elements[last].kind == tokenRest {
+		return n.elements[:last], true
+	}
+	return n.elements, false
+}
+
 func (p *pnParser) nextToken() {
 	p.skipWhite()
 	s := p.pos
@@ -0,0 +1,113 @@
+package pspec
+
+import (
+	"strings"
+	"testing"
+)
+
+var pnTestLocation = &testLocation{`test.pspec`, 1, 1}
+
+func TestFormatWrapsAtLineWidth(t *testing.T) {
+	x := `"` + strings.Repeat(`x`, 40) + `"`
+	y := `"` + strings.Repeat(`y`, 40) + `"`
+	z := `"` + strings.Repeat(`z`, 40) + `"`
+	for _, tc := range []struct {
+		name     string
+		pn       string
+		expected string
+	}{
+		{`fits on one line`, `[1 2 3]`, `[1 2 3]`},
+		{`list`, `[` + x + ` ` + y + ` ` + z + `]`, "[" + x + "\n " + y + "\n " + z + "]"},
+		{`call`, `(block [` + x + ` ` + y + `] ` + z + `)`, "(block\n  [" + x + " " + y + "]\n  " + z + ")"},
+		{`map keys are aligned`, `{:a ` + x + ` :bb ` + y + ` :ccc ` + z + `}`, "{:a " + x + "\n :bb " + y + "\n :ccc " + z + "}"},
+		{`map value is aligned after its key`, `{:key [` + x + ` ` + y + ` ` + z + `]}`,
+			"{:key [" + x + "\n       " + y + "\n       " + z + "]}"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := parsePN(pnTestLocation, tc.pn).pretty(); actual != tc.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPNDiffMessage(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected string
+		actual   string
+		message  string
+	}{
+		{`path into calls`, `(block (call "a") 1)`, `(block (call "b") 1)`,
+			"parse result differs from the expected PN\n  at block[0] > call[0]:\n    expected: \"a\"\n    actual:   \"b\""},
+		{`path into maps and lists`, `{:a [1 2] :b 3}`, `{:a [1 4] :b 3}`,
+			"parse result differs from the expected PN\n  at :a > [1]:\n    expected: 2\n    actual:   4"},
+		{`different lengths`, `[1 2]`, `[1]`,
+			"parse result differs from the expected PN\n  at (top):\n    expected: [1 2]\n    actual:   [1]"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := pnDiffMessage(parsePNPattern(pnTestLocation, tc.expected), parsePN(pnTestLocation, tc.actual))
+			if msg != tc.message {
+				t.Errorf("expected\n%s\ngot\n%s", tc.message, msg)
+			}
+		})
+	}
+}
+
+func TestPNPatternMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		pn      string
+		matches bool
+	}{
+		{`(call _* ...)`, `(call 1 2 3)`, true},
+		{`(call _*)`, `(call 1 2)`, false},
+		{`(call ...)`, `(call)`, true},
+		{`[1 ...]`, `[1]`, true},
+		{`[1 ...]`, `[2 1]`, false},
+		{`{:a _*}`, `{:a [1 2]}`, true},
+		{`{:a _*}`, `{:b 1}`, false},
+		{`_*`, `(block 1)`, true},
+	} {
+		t.Run(tc.pattern+` `+tc.pn, func(t *testing.T) {
+			if m := parsePNPattern(pnTestLocation, tc.pattern).matches(parsePN(pnTestLocation, tc.pn)); m != tc.matches {
+				t.Errorf("expected %t, got %t", tc.matches, m)
+			}
+		})
+	}
+}
+
+func TestPNWildcardParsing(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		valid   bool
+	}{
+		{`[_* ...]`, true},
+		{`(call ...)`, true},
+		{`[... 1]`, false},
+		{`{...}`, false},
+		{`{:a ...}`, false},
+	} {
+		t.Run(tc.pattern, func(t *testing.T) {
+			valid := true
+			func() {
+				defer func() {
+					if recover() != nil {
+						valid = false
+					}
+				}()
+				parsePNPattern(pnTestLocation, tc.pattern)
+			}()
+			if valid != tc.valid {
+				t.Errorf("expected valid to be %t, got %t", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestPNNodeOfKeepsStructure(t *testing.T) {
+	text := `(block {:a [1 2.5 "s"] :b nil :c true} (call))`
+	if actual := pnNodeOf(parsePN(pnTestLocation, text).toPN()).String(); actual != text {
+		t.Errorf("expected %s, got %s", text, actual)
+	}
+}
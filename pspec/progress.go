@@ -0,0 +1,62 @@
+package pspec
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type progressReporter struct {
+	lock sync.Mutex
+	out  io.Writer
+}
+
+// NewProgressReporter returns a Reporter that writes one character for each example to the given writer:
+// a '.' for a passed example, an 'F' for a failed example, a '*' for a pending example, and an 'S' for
+// a skipped example.
+func NewProgressReporter(out io.Writer) Reporter {
+	return &progressReporter{out: out}
+}
+
+func (r *progressReporter) GroupStarted(path []string, group *TestGroup) {
+}
+
+func (r *progressReporter) GroupFinished(path []string, group *TestGroup, elapsed time.Duration) {
+}
+
+func (r *progressReporter) GroupFailed(path []string, group *TestGroup, message string) {
+}
+
+func (r *progressReporter) ExampleStarted(path []string, example *TestExecutable) {
+}
+
+func (r *progressReporter) ExamplePassed(path []string, example *TestExecutable, elapsed time.Duration) {
+	r.write(`.`)
+}
+
+func (r *progressReporter) ExampleFailed(path []string, example *TestExecutable, elapsed time.Duration, message string) {
+	r.write(`F`)
+}
+
+func (r *progressReporter) ExampleSkipped(path []string, example *TestExecutable, reason string) {
+	r.write(`S`)
+}
+
+func (r *progressReporter) ExamplePending(path []string, example *TestExecutable, elapsed time.Duration, reason string) {
+	r.write(`*`)
+}
+
+func (r *progressReporter) TearDownFailed(path []string, example *TestExecutable, err error) {
+}
+
+func (r *progressReporter) Done() error {
+	r.write("\n")
+	return nil
+}
+
+func (r *progressReporter) write(s string) {
+	r.lock.Lock()
+	fmt.Fprint(r.out, s)
+	r.lock.Unlock()
+}
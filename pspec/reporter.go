@@ -0,0 +1,105 @@
+package pspec
+
+import (
+	"time"
+)
+
+type (
+	// Reporter receives notifications about the progress of a test run. The path passed to each
+	// method contains the names of the groups that enclose the reported group or example.
+	Reporter interface {
+		GroupStarted(path []string, group *TestGroup)
+		GroupFinished(path []string, group *TestGroup, elapsed time.Duration)
+
+		// GroupFailed is called before GroupFinished when the After_all hooks of a group failed
+		GroupFailed(path []string, group *TestGroup, message string)
+
+		ExampleStarted(path []string, example *TestExecutable)
+		ExamplePassed(path []string, example *TestExecutable, elapsed time.Duration)
+		ExampleFailed(path []string, example *TestExecutable, elapsed time.Duration, message string)
+
+		// ExampleSkipped is called instead of ExampleStarted for an example that is not run
+		ExampleSkipped(path []string, example *TestExecutable, reason string)
+
+		// ExamplePending is called when an example that is marked as pending fails as expected
+		ExamplePending(path []string, example *TestExecutable, elapsed time.Duration, reason string)
+
+		// TearDownFailed is called when a tear down that ran after an example raised an error
+		TearDownFailed(path []string, example *TestExecutable, err error)
+
+		// Done is called once when all tests have run
+		Done() error
+	}
+
+	reporters []Reporter
+)
+
+func (rs reporters) GroupStarted(path []string, group *TestGroup) {
+	for _, r := range rs {
+		r.GroupStarted(path, group)
+	}
+}
+
+func (rs reporters) GroupFinished(path []string, group *TestGroup, elapsed time.Duration) {
+	for _, r := range rs {
+		r.GroupFinished(path, group, elapsed)
+	}
+}
+
+func (rs reporters) GroupFailed(path []string, group *TestGroup, message string) {
+	for _, r := range rs {
+		r.GroupFailed(path, group, message)
+	}
+}
+
+func (rs reporters) ExampleStarted(path []string, example *TestExecutable) {
+	for _, r := range rs {
+		r.ExampleStarted(path, example)
+	}
+}
+
+func (rs reporters) ExamplePassed(path []string, example *TestExecutable, elapsed time.Duration) {
+	for _, r := range rs {
+		r.ExamplePassed(path, example, elapsed)
+	}
+}
+
+func (rs reporters) ExampleFailed(path []string, example *TestExecutable, elapsed time.Duration, message string) {
+	for _, r := range rs {
+		r.ExampleFailed(path, example, elapsed, message)
+	}
+}
+
+func (rs reporters) ExampleSkipped(path []string, example *TestExecutable, reason string) {
+	for _, r := range rs {
+		r.ExampleSkipped(path, example, reason)
+	}
+}
+
+func (rs reporters) ExamplePending(path []string, example *TestExecutable, elapsed time.Duration, reason string) {
+	for _, r := range rs {
+		r.ExamplePending(path, example, elapsed, reason)
+	}
+}
+
+func (rs reporters) TearDownFailed(path []string, example *TestExecutable, err error) {
+	for _, r := range rs {
+		r.TearDownFailed(path, example, err)
+	}
+}
+
+func (rs reporters) Done() (err error) {
+	for _, r := range rs {
+		if e := r.Done(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// childPath returns a copy of path with name appended
+func childPath(path []string, name string) []string {
+	cp := make([]string, len(path), len(path)+1)
+	copy(cp, path)
+	return append(cp, name)
+}
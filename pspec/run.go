@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lyraproj/pcore/pcore"
 	"github.com/lyraproj/pcore/px"
@@ -16,9 +17,79 @@ import (
 	_ "github.com/lyraproj/puppet-evaluator/functions"
 )
 
-func RunPspecTests(t *testing.T, testRoot string, initializer func() px.DefiningLoader) {
+func RunPspecTests(t *testing.T, testRoot string, initializer func() px.DefiningLoader, opts ...Option) {
 	t.Helper()
 
+	o := newOptions(opts)
+	if o.err != nil {
+		t.Fatal(o.err.Error())
+	}
+	testFiles, err := FindTestFiles(testRoot)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	tests, err := LoadTests(testFiles, initializer)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if tests, err = o.selectTests(tests); err != nil {
+		t.Fatal(err.Error())
+	}
+	if o.dryRun {
+		b := strings.Builder{}
+		ListTests(&b, tests)
+		t.Log("\n" + b.String())
+		return
+	}
+	if msg := o.onlyFailuresMessage(); msg != `` {
+		t.Log(msg)
+	}
+	if msg := o.seedMessage(); msg != `` {
+		t.Log(msg)
+	}
+	// All examples run in one subtest. It doesn't return until its parallel subtests have completed, so
+	// the messages and reports below cover all examples.
+	t.Run(filepath.Base(testRoot), func(s *testing.T) {
+		o.runTests(s, tests, nil, []string{})
+	})
+	if msg := o.notRunMessage(); msg != `` {
+		t.Log(msg)
+	}
+	if msg, err := o.goldenMessage(); err != nil {
+		t.Error(err.Error())
+	} else if msg != `` {
+		t.Log(msg)
+	}
+	if err = o.reporters.Done(); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+// FindTestFiles returns the paths of all files with the suffix .pspec that are found
+// when walking the given roots. A root that appoints a file is included as is.
+func FindTestFiles(roots ...string) ([]string, error) {
+	testFiles := make([]string, 0, 64)
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err == nil {
+				if !info.IsDir() && (path == root || strings.HasSuffix(path, `.pspec`)) {
+					testFiles = append(testFiles, path)
+				}
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return testFiles, nil
+}
+
+// LoadTests parses the given test files and returns the tests that they declare. The optional
+// initializer is called once, prior to loading the tests, and may define additional functions
+// and types.
+func LoadTests(testFiles []string, initializer func() px.DefiningLoader) ([]Test, error) {
 	if initializer != nil {
 		err := pcore.Try(func(c px.Context) error {
 			c.DoWithLoader(initializer(), func() { px.ResolveResolvables(c) })
@@ -29,73 +100,66 @@ func RunPspecTests(t *testing.T, testRoot string, initializer func() px.Defining
 		}
 	}
 
-	testFiles := make([]string, 0, 64)
-	err := filepath.Walk(testRoot, func(path string, info os.FileInfo, err error) error {
-		if err == nil {
-			if !info.IsDir() && strings.HasSuffix(path, `.pspec`) {
-				testFiles = append(testFiles, path)
-			}
-		}
-		return err
-	})
-	if err != nil {
-		t.Errorf(err.Error())
-		return
-	}
-	tests := make([]Test, 0, 100)
+	// All files are evaluated before the tests are created so that shared examples can be
+	// used in files other than the one where they are declared.
+	nodes := make([]Node, 0, 100)
 	c := evaluator.NewContext(NewSpecEvaluator, px.NewParentedLoader(pcore.SystemLoader()), pcore.Logger())
 	for _, testFile := range testFiles {
-		tests = append(tests, CreateTests(c, parseTestContents(t, testFile))...)
+		expr, err := parseTestContents(testFile)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, CreateNodes(c, expr)...)
 	}
-	runTests(t, tests, nil)
+	return createTests(nodes), nil
 }
 
-func runTests(t *testing.T, tests []Test, parentContext *TestContext) {
+func (o *options) runTests(t *testing.T, tests []Test, parentContext *TestContext, path []string) {
 	t.Helper()
 
 	for _, test := range tests {
-		ctx := &TestContext{
-			parent:         parentContext,
-			tearDowns:      make([]Housekeeping, 0),
-			accessedValues: make(map[int64]px.Value, 32),
-			node:           test.Node()}
+		ctx := newTestContext(parentContext, test.Node())
 
 		if testExec, ok := test.(*TestExecutable); ok {
 			t.Run(testExec.Name(), func(s *testing.T) {
-				testExec.Run(ctx, &assertions{s})
+				if o.concurrent(ctx) {
+					s.Parallel()
+					defer o.acquire()()
+				}
+				switch result, messages := o.runExample(path, ctx, testExec); result {
+				case outcomeFailed:
+					for _, m := range messages {
+						s.Error(m)
+					}
+				case outcomePending:
+					s.Skip(`pending: ` + messages[0])
+				case outcomeSkipped, outcomeNotRun:
+					s.Skip(messages[0])
+				}
 			})
 		} else if testGroup, ok := test.(*TestGroup); ok {
-			t.Run(testGroup.Name(), func(s *testing.T) {
-				runTests(s, testGroup.Tests(), ctx)
+			// The group hooks run outside of the subtest of the group because a subtest doesn't return until
+			// all of its parallel subtests have completed. The After_all hooks will therefore not run until
+			// all examples of the group are done.
+			start := time.Now()
+			o.reporters.GroupStarted(path, testGroup)
+			failures := o.runGroup(path, testGroup, ctx, func() {
+				t.Run(testGroup.Name(), func(s *testing.T) {
+					o.runTests(s, testGroup.Tests(), ctx, childPath(path, testGroup.Name()))
+				})
 			})
+			for _, f := range failures {
+				t.Errorf(`%s: %s`, testGroup.Name(), f)
+			}
+			o.reporters.GroupFinished(path, testGroup, time.Since(start))
 		}
 	}
 }
 
-func parseTestContents(t *testing.T, path string) parser.Expression {
+func parseTestContents(path string) (parser.Expression, error) {
 	content, err := ioutil.ReadFile(path)
 	if err != nil {
-		t.Fatal(err)
-	}
-
-	expr, err := parser.CreatePspecParser().Parse(path, string(content), false)
-	if err != nil {
-		t.Fatal(err.Error())
-	}
-	return expr
-}
-
-type assertions struct {
-	t *testing.T
-}
-
-func (a *assertions) Fail(message string) {
-	a.t.Error(message)
-	a.t.FailNow()
-}
-
-func (a *assertions) AssertEquals(expected interface{}, actual interface{}) {
-	if !px.Equals(expected, actual, nil) {
-		a.t.Errorf("expected %T '%v', got %T '%v'\n", expected, expected, actual, actual)
+		return nil, err
 	}
+	return parser.CreatePspecParser().Parse(path, string(content), false)
 }
@@ -0,0 +1,361 @@
+package pspec
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+)
+
+type (
+	// Runner runs tests without the aid of a testing.T and writes the outcome to a writer
+	Runner struct {
+		lock       sync.Mutex
+		options    *options
+		out        io.Writer
+		verbose    bool
+		passed     int
+		failed     int
+		pending    int
+		skipped    int
+		notRun     int
+		hookFailed int
+	}
+
+	// outcome is the outcome of running one example
+	outcome int
+
+	runnerAssertions struct {
+		location issue.Location
+		failures []string
+		golden   *goldenUpdates
+	}
+
+	// failNow is the panic value used by runnerAssertions to abort an example
+	failNow struct{}
+)
+
+const (
+	outcomePassed = outcome(iota)
+	outcomeFailed
+	outcomePending
+	outcomeSkipped
+	outcomeNotRun
+)
+
+func NewRunner(out io.Writer, verbose bool, opts ...Option) *Runner {
+	return &Runner{options: newOptions(opts), out: out, verbose: verbose}
+}
+
+// Run runs the given tests and returns true when no test failed. The tests are listed rather than run
+// when the DryRun option is in effect.
+func (r *Runner) Run(tests []Test) bool {
+	if r.options.err != nil {
+		fmt.Fprintln(r.out, r.options.err.Error())
+		return false
+	}
+	tests, err := r.options.selectTests(tests)
+	if err != nil {
+		fmt.Fprintln(r.out, err.Error())
+		return false
+	}
+	if r.options.dryRun {
+		ListTests(r.out, tests)
+		return true
+	}
+	if msg := r.options.onlyFailuresMessage(); msg != `` {
+		fmt.Fprintln(r.out, msg)
+	}
+	r.runTests(tests, nil, []string{})
+	fmt.Fprintf(r.out, "\n%d examples, %d failures", r.passed+r.failed+r.pending+r.skipped+r.notRun, r.failed)
+	if r.pending > 0 {
+		fmt.Fprintf(r.out, ", %d pending", r.pending)
+	}
+	if r.skipped > 0 {
+		fmt.Fprintf(r.out, ", %d skipped", r.skipped)
+	}
+	if r.notRun > 0 {
+		fmt.Fprintf(r.out, ", %d not run", r.notRun)
+	}
+	if r.hookFailed > 0 {
+		fmt.Fprintf(r.out, ", %d failed hooks", r.hookFailed)
+	}
+	fmt.Fprintln(r.out)
+	if msg := r.options.notRunMessage(); msg != `` {
+		fmt.Fprintln(r.out, msg)
+	}
+	if msg, err := r.options.goldenMessage(); err != nil {
+		fmt.Fprintln(r.out, err.Error())
+		return false
+	} else if msg != `` {
+		fmt.Fprintln(r.out, msg)
+	}
+	if msg := r.options.seedMessage(); msg != `` {
+		fmt.Fprintln(r.out, msg)
+	}
+	if err := r.options.reporters.Done(); err != nil {
+		fmt.Fprintln(r.out, err.Error())
+		return false
+	}
+	return r.failed == 0 && r.hookFailed == 0
+}
+
+// runTests runs the given tests. Examples that may run concurrently are started in goroutines of their
+// own and the function doesn't return until all of them have completed.
+func (r *Runner) runTests(tests []Test, parentContext *TestContext, path []string) {
+	rs := r.options.reporters
+	wg := sync.WaitGroup{}
+	for _, test := range tests {
+		ctx := newTestContext(parentContext, test.Node())
+		name := strings.Join(childPath(path, test.Name()), `/`)
+
+		if testExec, ok := test.(*TestExecutable); ok {
+			if r.options.concurrent(ctx) {
+				wg.Add(1)
+				release := r.options.acquire()
+				go func() {
+					defer wg.Done()
+					defer release()
+					r.report(name, ctx, testExec, path)
+				}()
+			} else {
+				r.report(name, ctx, testExec, path)
+			}
+		} else if testGroup, ok := test.(*TestGroup); ok {
+			start := time.Now()
+			rs.GroupStarted(path, testGroup)
+			failures := r.options.runGroup(path, testGroup, ctx, func() { r.runTests(testGroup.Tests(), ctx, childPath(path, testGroup.Name())) })
+			if len(failures) > 0 {
+				// A failed hook is not a failed example and is therefore counted separately
+				r.lock.Lock()
+				r.hookFailed++
+				fmt.Fprintf(r.out, "--- FAIL: %s\n", name)
+				for _, f := range failures {
+					fmt.Fprintf(r.out, "    %s\n", strings.Replace(strings.TrimSpace(f), "\n", "\n    ", -1))
+				}
+				r.lock.Unlock()
+			}
+			rs.GroupFinished(path, testGroup, time.Since(start))
+		}
+	}
+	wg.Wait()
+}
+
+// report runs the given example and writes its outcome
+func (r *Runner) report(name string, ctx *TestContext, example *TestExecutable, path []string) {
+	result, messages := r.options.runExample(path, ctx, example)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch result {
+	case outcomeFailed:
+		r.failed++
+		fmt.Fprintf(r.out, "--- FAIL: %s\n", name)
+		for _, f := range messages {
+			fmt.Fprintf(r.out, "    %s\n", strings.Replace(strings.TrimSpace(f), "\n", "\n    ", -1))
+		}
+	case outcomePending:
+		r.pending++
+		if r.verbose {
+			fmt.Fprintf(r.out, "--- PENDING: %s (%s)\n", name, messages[0])
+		}
+	case outcomeSkipped:
+		r.skipped++
+		if r.verbose {
+			fmt.Fprintf(r.out, "--- SKIP: %s (%s)\n", name, messages[0])
+		}
+	case outcomeNotRun:
+		r.notRun++
+	default:
+		r.passed++
+		if r.verbose {
+			fmt.Fprintf(r.out, "--- PASS: %s\n", name)
+		}
+	}
+}
+
+// runExample runs the given example, unless it is skipped or the maximum number of failures has been reached,
+// and notifies the reporters of the options. The returned messages are the failures of a failed example or
+// the reason for a pending, skipped, or not run example.
+func (o *options) runExample(path []string, ctx *TestContext, example *TestExecutable) (outcome, []string) {
+	rs := o.reporters
+	// A skipped example is always counted as skipped so that the number of examples that were not run
+	// only depends on the examples that would have run
+	if reason, ok := ctx.skipReason(); ok {
+		rs.ExampleSkipped(path, example, reason)
+		return outcomeSkipped, []string{reason}
+	}
+	if o.exhausted() {
+		atomic.AddInt32(&o.notRun, 1)
+		reason := `not run since the maximum number of failures was reached`
+		rs.ExampleSkipped(path, example, reason)
+		return outcomeNotRun, []string{reason}
+	}
+	if failures := ctx.beforeAllFailures(); len(failures) > 0 {
+		rs.ExampleStarted(path, example)
+		rs.ExampleFailed(path, example, 0, strings.Join(failures, "\n"))
+		o.recordFailure()
+		return outcomeFailed, failures
+	}
+
+	hold := ctx.isolate(o.concurrent(ctx))
+	if hold == nil {
+		atomic.AddInt32(&o.blocked, 1)
+		reason := `not run since it would have to wait for an example that timed out`
+		rs.ExampleSkipped(path, example, reason)
+		return outcomeNotRun, []string{reason}
+	}
+
+	start := time.Now()
+	rs.ExampleStarted(path, example)
+	a := &runnerAssertions{location: example.Node().Location(), failures: make([]string, 0), golden: o.golden}
+	timeout := ctx.timeout(o.timeout)
+	completed := runWithTimeout(timeout, func() {
+		defer settingsLock.release(hold)
+		a.run(func() { example.Run(ctx, a) })
+	}, func() { settingsLock.stall(hold) })
+	elapsed := time.Since(start)
+
+	result := outcomePassed
+	messages := a.failures
+	if !completed {
+		// The example is still running and must be left behind. It keeps its hold of the settingsLock until
+		// it returns. Its failures and tear downs can't be reported since they are not safe to read.
+		messages = []string{atLocation(a.location, fmt.Sprintf(
+			`example timed out after %s and is left running, its tear downs will not be reported`, timeout))}
+		rs.ExampleFailed(path, example, elapsed, messages[0])
+		o.recordFailure()
+		return outcomeFailed, messages
+	}
+	if reason, ok := ctx.pendingReason(); ok {
+		if len(messages) > 0 {
+			rs.ExamplePending(path, example, elapsed, reason)
+			result = outcomePending
+			messages = []string{reason}
+		} else {
+			messages = []string{atLocation(a.location, fmt.Sprintf(`expected pending example to fail: %s`, reason))}
+		}
+	}
+	if result != outcomePending {
+		if len(messages) > 0 {
+			rs.ExampleFailed(path, example, elapsed, strings.Join(messages, "\n"))
+			o.recordFailure()
+			result = outcomeFailed
+		} else {
+			rs.ExamplePassed(path, example, elapsed)
+		}
+	}
+	for _, err := range ctx.TearDownErrors() {
+		rs.TearDownFailed(path, example, err)
+	}
+	return result, messages
+}
+
+// runWithTimeout calls the given function and waits for it to return. The wait is abandoned when the given
+// timeout is exceeded, in which case the given abandon function is called and false is returned. A zero
+// timeout means that there's no time limit.
+func runWithTimeout(timeout time.Duration, f func(), abandon func()) bool {
+	if timeout <= 0 {
+		f()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		abandon()
+		return false
+	}
+}
+
+// runGroup runs the Before_all hooks of the given group, calls the given function to run the tests of the
+// group, and then runs the After_all hooks and the tear downs of the group. The failures of the After_all
+// hooks are reported and returned. No hooks are run when the maximum number of failures has been reached.
+func (o *options) runGroup(path []string, group *TestGroup, ctx *TestContext, runTests func()) []string {
+	if o.exhausted() {
+		runTests()
+		return nil
+	}
+	ctx.hookFailures = runGroupHooks(ctx, beforeAll)
+	runTests()
+	failures := runGroupHooks(ctx, afterAll)
+	ctx.tearDown()
+	if len(failures) > 0 {
+		o.reporters.GroupFailed(path, group, strings.Join(failures, "\n"))
+		o.recordFailure()
+	}
+	return failures
+}
+
+// runGroupHooks runs the hooks of the given kind that are declared by the group of the given context
+// and returns the failures
+func runGroupHooks(ctx *TestContext, kind hookKind) []string {
+	if len(ctx.node.hooks(kind)) == 0 {
+		return nil
+	}
+	hold := settingsLock.acquire(true)
+	if hold == nil {
+		return []string{hookNames[kind] + `: not run since it would have to wait for an example that timed out`}
+	}
+	defer settingsLock.release(hold)
+	ctx.applySettings()
+	a := &runnerAssertions{location: ctx.node.Location(), failures: make([]string, 0)}
+	a.run(func() { ctx.runHooks(kind, a) })
+	for i, f := range a.failures {
+		a.failures[i] = hookNames[kind] + `: ` + f
+	}
+	return a.failures
+}
+
+func (a *runnerAssertions) Fail(message string) {
+	a.failures = append(a.failures, atLocation(a.location, message))
+	panic(failNow{})
+}
+
+func (a *runnerAssertions) AssertEquals(expected interface{}, actual interface{}) {
+	if !px.Equals(expected, actual, nil) {
+		a.failures = append(a.failures, atLocation(a.location, equalsMessage(expected, actual)))
+	}
+}
+
+func (a *runnerAssertions) updateGolden(location issue.Location, replacement string) bool {
+	if a.golden == nil {
+		return false
+	}
+	a.golden.add(location, replacement)
+	return true
+}
+
+func (a *runnerAssertions) updating() bool {
+	return a.golden != nil
+}
+
+func (a *runnerAssertions) snapshotWritten() {
+	a.golden.snapshotWritten()
+}
+
+// run calls the given function and records a failure for any panic that it raises
+func (a *runnerAssertions) run(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case failNow:
+			case error:
+				a.failures = append(a.failures, atLocation(a.location, r.Error()))
+			default:
+				a.failures = append(a.failures, atLocation(a.location, fmt.Sprint(r)))
+			}
+		}
+	}()
+	f()
+}
@@ -0,0 +1,197 @@
+package pspec
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type fileLine struct {
+	file string
+	line int
+}
+
+// selectTests returns the tests that are accepted by the given function together with the groups that
+// contain them. Groups that end up without tests are pruned. A group that is accepted is kept with all
+// its tests. The ancestors passed to the function are the groups that enclose the test.
+func selectTests(tests []Test, ancestors []Test, accept func(ancestors []Test, test Test) bool) []Test {
+	result := make([]Test, 0, len(tests))
+	for _, test := range tests {
+		if accept(ancestors, test) {
+			result = append(result, test)
+			continue
+		}
+		if g, ok := test.(*TestGroup); ok {
+			children := selectTests(g.Tests(), childTests(ancestors, g), accept)
+			if len(children) > 0 {
+				result = append(result, &TestGroup{testNode: g.testNode, tests: children})
+			}
+		}
+	}
+	return result
+}
+
+// eachTest calls the given function for each test in the tree, depth first
+func eachTest(tests []Test, ancestors []Test, f func(ancestors []Test, test Test)) {
+	for _, test := range tests {
+		f(ancestors, test)
+		if g, ok := test.(*TestGroup); ok {
+			eachTest(g.Tests(), childTests(ancestors, g), f)
+		}
+	}
+}
+
+// childTests returns a copy of tests with test appended
+func childTests(tests []Test, test Test) []Test {
+	ct := make([]Test, len(tests), len(tests)+1)
+	copy(ct, tests)
+	return append(ct, test)
+}
+
+// testNames returns the names of the given tests
+func testNames(tests []Test) []string {
+	names := make([]string, len(tests))
+	for i, test := range tests {
+		names[i] = test.Name()
+	}
+	return names
+}
+
+// FullName returns the names in the given path and the given name joined with a space
+func FullName(path []string, name string) string {
+	return strings.Join(childPath(path, name), ` `)
+}
+
+// filterByName selects the examples whose full name matches the given pattern
+func filterByName(tests []Test, pattern *regexp.Regexp) []Test {
+	return selectTests(tests, []Test{}, func(ancestors []Test, test Test) bool {
+		_, isExample := test.(*TestExecutable)
+		return isExample && pattern.MatchString(FullName(testNames(ancestors), test.Name()))
+	})
+}
+
+// filterByLines selects, for each of the given file lines, the nodes that are declared on that line or,
+// when no such node exists, the closest nodes that are declared above it in the same file. An error is
+// returned when no node is declared on or above one of the lines.
+func filterByLines(tests []Test, fls []fileLine) ([]Test, error) {
+	found := make([]fileLine, len(fls))
+	for i, fl := range fls {
+		line := closestLine(tests, fl)
+		if line == 0 {
+			return nil, fmt.Errorf(`no example at %s:%d`, fl.file, fl.line)
+		}
+		found[i] = fileLine{fl.file, line}
+	}
+	return selectTests(tests, []Test{}, func(ancestors []Test, test Test) bool {
+		if loc := test.Node().Location(); loc != nil {
+			for _, fl := range found {
+				if loc.Line() == fl.line && sameFile(loc.File(), fl.file) {
+					return true
+				}
+			}
+		}
+		return false
+	}), nil
+}
+
+// closestLine returns the line of the node declared closest above or on the given line in the given file, or
+// zero when no such node exists
+func closestLine(tests []Test, fl fileLine) int {
+	found := 0
+	eachTest(tests, []Test{}, func(ancestors []Test, test Test) {
+		if loc := test.Node().Location(); loc != nil && sameFile(loc.File(), fl.file) {
+			if line := loc.Line(); line <= fl.line && line > found {
+				found = line
+			}
+		}
+	})
+	return found
+}
+
+// filterByTags selects the examples that have at least one of the included tags, unless the includes
+// are empty, and none of the excluded tags. Tags are inherited from enclosing groups.
+func filterByTags(tests []Test, includes, excludes []string) []Test {
+	return selectTests(tests, []Test{}, func(ancestors []Test, test Test) bool {
+		if _, isExample := test.(*TestExecutable); !isExample {
+			return false
+		}
+		tags := inheritedTags(ancestors, test)
+		for _, tag := range excludes {
+			if tags[tag] {
+				return false
+			}
+		}
+		if len(includes) == 0 {
+			return true
+		}
+		for _, tag := range includes {
+			if tags[tag] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// inheritedTags returns the set of tags that are declared by the given test and its ancestors
+func inheritedTags(ancestors []Test, test Test) map[string]bool {
+	tags := make(map[string]bool)
+	for _, t := range childTests(ancestors, test) {
+		for _, tag := range t.Node().Tags() {
+			tags[tag] = true
+		}
+	}
+	return tags
+}
+
+// hasFocus returns true if any test in the given tree is focused
+func hasFocus(tests []Test) bool {
+	focused := false
+	eachTest(tests, []Test{}, func(ancestors []Test, test Test) {
+		focused = focused || test.Node().Focused()
+	})
+	return focused
+}
+
+// filterByFocus selects the focused tests
+func filterByFocus(tests []Test) []Test {
+	return selectTests(tests, []Test{}, func(ancestors []Test, test Test) bool {
+		return test.Node().Focused()
+	})
+}
+
+// sameFile returns true if the two paths appoint the same file. The second path may be a suffix of the
+// first path.
+func sameFile(path, suffix string) bool {
+	path = filepath.Clean(path)
+	suffix = filepath.Clean(suffix)
+	if path == suffix || strings.HasSuffix(path, string(filepath.Separator)+suffix) {
+		return true
+	}
+	ap, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	as, err := filepath.Abs(suffix)
+	return err == nil && ap == as
+}
+
+// parseFileLines parses a comma separated list of <file>:<line> entries
+func parseFileLines(s string) ([]fileLine, error) {
+	entries := strings.Split(s, `,`)
+	fls := make([]fileLine, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		colon := strings.LastIndexByte(e, ':')
+		if colon > 0 {
+			if line, err := strconv.Atoi(e[colon+1:]); err == nil {
+				fls = append(fls, fileLine{e[:colon], line})
+				continue
+			}
+		}
+		return nil, fmt.Errorf(`invalid <file>:<line> '%s'`, e)
+	}
+	return fls, nil
+}
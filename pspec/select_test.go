@@ -0,0 +1,103 @@
+package pspec
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// selectionTree returns the tests declared by the following spec in file a.pspec:
+//
+//	1 Examples('math', Tags('slow'),
+//	2   Example('adds', ...),
+//	3
+//	4   Example('subtracts', Tags('fast'), ...),
+//	5
+//	6   Examples('nested',
+//	7     Example('multiplies', ...))),
+//	8
+//	9
+//	10 Example('top', ...)
+func selectionTree(focused ...string) []Test {
+	adds := testExample(`adds`, `a.pspec`, 2)
+	subtracts := testExample(`subtracts`, `a.pspec`, 4)
+	subtracts.Node().(*Example).tags = []string{`fast`}
+	multiplies := testExample(`multiplies`, `a.pspec`, 7)
+	nested := testGroup(`nested`, `a.pspec`, 6, multiplies)
+	math := testGroup(`math`, `a.pspec`, 1, adds, subtracts, nested)
+	math.Node().(*Examples).tags = []string{`slow`}
+	tests := []Test{math, testExample(`top`, `a.pspec`, 10)}
+	eachTest(tests, []Test{}, func(ancestors []Test, test Test) {
+		for _, name := range focused {
+			if test.Name() == name {
+				switch n := test.Node().(type) {
+				case *Example:
+					n.focused = true
+				case *Examples:
+					n.focused = true
+				}
+			}
+		}
+	})
+	return tests
+}
+
+// exampleNames returns the full names of the examples in the given tree
+func exampleNames(tests []Test) []string {
+	names := make([]string, 0)
+	eachTest(tests, []Test{}, func(ancestors []Test, test Test) {
+		if _, ok := test.(*TestExecutable); ok {
+			names = append(names, FullName(testNames(ancestors), test.Name()))
+		}
+	})
+	return names
+}
+
+func TestSelectTests(t *testing.T) {
+	all := []string{`math adds`, `math subtracts`, `math nested multiplies`, `top`}
+	mathExamples := []string{`math adds`, `math subtracts`, `math nested multiplies`}
+
+	for _, tc := range []struct {
+		name     string
+		options  *options
+		focused  []string
+		expected []string
+		err      string
+	}{
+		{`no selection`, &options{}, nil, all, ``},
+		{`filter on example name`, &options{filter: regexp.MustCompile(`subtracts`)}, nil, []string{`math subtracts`}, ``},
+		{`filter on group name`, &options{filter: regexp.MustCompile(`^math `)}, nil, mathExamples, ``},
+		{`filter that matches nothing`, &options{filter: regexp.MustCompile(`divides`)}, nil, []string{}, ``},
+		{`line of an example`, &options{lines: []fileLine{{`a.pspec`, 4}}}, nil, []string{`math subtracts`}, ``},
+		{`line below an example`, &options{lines: []fileLine{{`a.pspec`, 5}}}, nil, []string{`math subtracts`}, ``},
+		{`line of a group`, &options{lines: []fileLine{{`a.pspec`, 1}}}, nil, mathExamples, ``},
+		{`line of a nested group`, &options{lines: []fileLine{{`a.pspec`, 6}}}, nil, []string{`math nested multiplies`}, ``},
+		{`multiple lines`, &options{lines: []fileLine{{`a.pspec`, 2}, {`a.pspec`, 12}}}, nil, []string{`math adds`, `top`}, ``},
+		{`line in another file`, &options{lines: []fileLine{{`b.pspec`, 4}}}, nil, nil, `no example at b.pspec:4`},
+		{`line above all examples`, &options{lines: []fileLine{{`a.pspec`, 0}}}, nil, nil, `no example at a.pspec:0`},
+		{`included tag`, &options{includes: []string{`fast`}}, nil, []string{`math subtracts`}, ``},
+		{`included tag of a group`, &options{includes: []string{`slow`}}, nil, mathExamples, ``},
+		{`excluded tag of a group`, &options{excludes: []string{`slow`}}, nil, []string{`top`}, ``},
+		{`included and excluded tags`, &options{includes: []string{`slow`}, excludes: []string{`fast`}}, nil,
+			[]string{`math adds`, `math nested multiplies`}, ``},
+		{`focused example`, &options{}, []string{`multiplies`}, []string{`math nested multiplies`}, ``},
+		{`focused group and example`, &options{}, []string{`nested`, `top`}, []string{`math nested multiplies`, `top`}, ``},
+		{`focused example and filter`, &options{filter: regexp.MustCompile(`adds`)}, []string{`multiplies`}, []string{}, ``},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tests, err := tc.options.selectTests(selectionTree(tc.focused...))
+			if tc.err != `` {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf(`expected error %q, got %v`, tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := exampleNames(tests); !reflect.DeepEqual(tc.expected, names) {
+				t.Errorf(`expected %v, got %v`, tc.expected, names)
+			}
+		})
+	}
+}
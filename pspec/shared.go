@@ -0,0 +1,210 @@
+package pspec
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+type (
+	// SharedExamples is the result of a Shared_examples declaration. It is not a Node and will
+	// therefore not produce tests unless it is used by an It_behaves_like node.
+	SharedExamples struct {
+		examples *Examples
+	}
+
+	// BehavesLike is an It_behaves_like node. It behaves as an Examples node with the children,
+	// lets, givens, hooks, and tags of the shared examples with the given name. Lets declared by
+	// the node itself takes precedence over those of the shared examples.
+	BehavesLike struct {
+		node
+		name     string
+		registry map[string]*Examples
+		lock     sync.Mutex
+		shared   *Examples
+	}
+)
+
+const sharedExamples = `sharedExamples`
+
+// CreateTest returns a group whose tests are created when they are first requested. The shared examples
+// may therefore be declared by an expression that is evaluated after the one that declares this node.
+func (b *BehavesLike) CreateTest() Test {
+	return &TestGroup{testNode: testNode{b}, resolve: b.createTests}
+}
+
+func (b *BehavesLike) createTests() []Test {
+	shared := b.sharedExamples()
+	if shared == nil {
+		return []Test{b.failure(px.Error(UnknownSharedExamples, issue.H{`name`: b.name}))}
+	}
+	if cycle := sharedCycle(b.registry, shared.children, []string{b.name}); cycle != nil {
+		return []Test{b.failure(px.Error(RecursiveSharedExamples, issue.H{`name`: b.name, `cycle`: strings.Join(cycle, ` -> `)}))}
+	}
+	tests := make([]Test, len(shared.children))
+	for idx, child := range shared.children {
+		tests[idx] = child.CreateTest()
+	}
+	return tests
+}
+
+// failure returns an executable that fails with the given error at the location of this node
+func (b *BehavesLike) failure(err error) Test {
+	return &TestExecutable{testNode{b}, func(context *TestContext, assertions Assertions) {
+		assertions.Fail(err.Error())
+	}}
+}
+
+// sharedExamples returns the shared examples that this node behaves like, or nil when no shared examples
+// with the name of this node have been declared
+func (b *BehavesLike) sharedExamples() *Examples {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.shared == nil {
+		b.shared = b.registry[b.name]
+	}
+	return b.shared
+}
+
+// sharedCycle returns the names of the shared examples that lead from the last name in the given chain
+// back to a name in the chain when the given nodes are expanded, or nil if the nodes don't lead back
+func sharedCycle(registry map[string]*Examples, nodes []Node, chain []string) []string {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *BehavesLike:
+			for _, name := range chain {
+				if name == n.name {
+					return childPath(chain, n.name)
+				}
+			}
+			if shared, ok := registry[n.name]; ok {
+				if cycle := sharedCycle(registry, shared.children, childPath(chain, n.name)); cycle != nil {
+					return cycle
+				}
+			}
+		case *Examples:
+			if cycle := sharedCycle(registry, n.children, chain); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func (b *BehavesLike) Get(key string) (LazyValue, bool) {
+	if v, ok := b.node.Get(key); ok {
+		return v, true
+	}
+	if shared := b.sharedExamples(); shared != nil {
+		return shared.Get(key)
+	}
+	return nil, false
+}
+
+func (b *BehavesLike) Tags() []string {
+	if shared := b.sharedExamples(); shared != nil {
+		return append(shared.Tags(), b.node.Tags()...)
+	}
+	return b.node.Tags()
+}
+
+func (b *BehavesLike) collectInputs(context *TestContext, inputs []Input) []Input {
+	inputs = b.node.collectInputs(context, inputs)
+	if shared := b.sharedExamples(); shared != nil && shared.given != nil {
+		inputs = append(inputs, shared.given.inputs...)
+	}
+	return inputs
+}
+
+func (b *BehavesLike) serial() bool {
+	if shared := b.sharedExamples(); shared != nil && shared.serial() {
+		return true
+	}
+	return b.node.serial()
+}
+
+func (b *BehavesLike) timeout() time.Duration {
+	if shared := b.sharedExamples(); b.node.timeout() == 0 && shared != nil {
+		return shared.timeout()
+	}
+	return b.node.timeout()
+}
+
+func (b *BehavesLike) hooks(kind hookKind) []*Hook {
+	if shared := b.sharedExamples(); shared != nil {
+		return append(shared.hooks(kind), b.node.hooks(kind)...)
+	}
+	return b.node.hooks(kind)
+}
+
+func init() {
+	px.NewGoConstructor2(`PSpec::Shared_examples`,
+		func(l px.LocalTypes) {
+			l.Type2(`Given`, types.NewGoRuntimeType(&Given{}))
+			l.Type2(`Let`, types.NewGoRuntimeType(&LazyValueLet{}))
+			l.Type2(`ExampleNode`, types.NewGoRuntimeType((*Node)(nil)))
+			l.Type(`Nodes`, `Variant[ExampleNode, Array[Nodes]]`)
+			l.Type2(`NodeOption`, types.NewGoRuntimeType((*NodeOption)(nil)))
+			l.Type2(`Hook`, types.NewGoRuntimeType(&Hook{}))
+		},
+		func(d px.Dispatch) {
+			d.Param(`String[1]`)
+			d.RepeatedParam(`Variant[Nodes,Let,Given,NodeOption,Hook]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				name := args[0].String()
+				registry := sharedRegistry(c)
+				if _, ok := registry[name]; ok {
+					panic(px.Error(DuplicateSharedExamples, issue.H{`name`: name}))
+				}
+				ex := newExamplesFromArgs(c, args, nil)
+				registry[name] = ex
+				return types.WrapRuntime(&SharedExamples{ex})
+			})
+		})
+
+	px.NewGoConstructor2(`PSpec::It_behaves_like`,
+		func(l px.LocalTypes) {
+			l.Type2(`Given`, types.NewGoRuntimeType(&Given{}))
+			l.Type2(`Let`, types.NewGoRuntimeType(&LazyValueLet{}))
+			l.Type2(`NodeOption`, types.NewGoRuntimeType((*NodeOption)(nil)))
+		},
+		func(d px.Dispatch) {
+			d.Param(`String[1]`)
+			d.RepeatedParam(`Variant[Let,Given,NodeOption]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				lets := make([]*LazyValueLet, 0)
+				var given *Given
+				options := make([]NodeOption, 0)
+				for _, arg := range args[1:] {
+					switch i := arg.(*types.RuntimeValue).Interface().(type) {
+					case *LazyValueLet:
+						lets = append(lets, i)
+					case *Given:
+						given = i
+					case NodeOption:
+						options = append(options, i)
+					}
+				}
+				name := args[0].String()
+				b := &BehavesLike{name: name, registry: sharedRegistry(c)}
+				b.node.initialize(`behaves like `+name, c.StackTop(), given)
+				b.addOptions(options)
+				b.addLetDefs(lets)
+				return types.WrapRuntime(b)
+			})
+		})
+}
+
+// sharedRegistry returns the registry of shared examples that is kept in the given context
+func sharedRegistry(c px.Context) map[string]*Examples {
+	if r, ok := c.Get(sharedExamples); ok {
+		return r.(map[string]*Examples)
+	}
+	r := make(map[string]*Examples)
+	c.Set(sharedExamples, r)
+	return r
+}
@@ -0,0 +1,60 @@
+package pspec
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/pcore/pcore"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/puppet-evaluator/evaluator"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+func TestRecursiveSharedExamplesFail(t *testing.T) {
+	out, ok := runSpec(t, `
+Shared_examples('a',
+  It_behaves_like('b'))
+
+Shared_examples('b',
+  Example('passes',
+    Given('1'),
+    Evaluates_to(1)),
+  It_behaves_like('a'))
+
+Examples('recursion',
+  It_behaves_like('a'),
+
+  Example('runs after the recursion',
+    Given('1 + 1'),
+    Evaluates_to(2)))
+`)
+	if ok {
+		t.Fatalf("expected the run to fail, got:\n%s", out)
+	}
+	if !strings.Contains(out, `test.pspec:12: `) || !strings.Contains(out, `behave like themselves: a -> b -> a`) {
+		t.Errorf("expected a recursion failure at the location of It_behaves_like, got:\n%s", out)
+	}
+	if !strings.Contains(out, `--- PASS: recursion/runs after the recursion`) {
+		t.Errorf("expected the run to continue after the recursion, got:\n%s", out)
+	}
+}
+
+func TestCreateTestsResolvesSharedExamplesDeclaredLater(t *testing.T) {
+	c := evaluator.NewContext(NewSpecEvaluator, px.NewParentedLoader(pcore.SystemLoader()), pcore.Logger())
+	create := func(file, content string) []Test {
+		expr, err := parser.CreatePspecParser().Parse(file, content, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return CreateTests(c, expr)
+	}
+
+	tests := create(`a.pspec`, `Examples('user', It_behaves_like('declared later'))`)
+	create(`b.pspec`, `Shared_examples('declared later', Example('adds', Given('1 + 1'), Evaluates_to(2)))`)
+
+	expected := []string{`user behaves like declared later adds`}
+	if names := exampleNames(tests); !reflect.DeepEqual(expected, names) {
+		t.Errorf(`expected %v, got %v`, expected, names)
+	}
+}
@@ -0,0 +1,19 @@
+package pspec
+
+import "math/rand"
+
+// shuffleTests returns a copy of the given tests where the siblings at each level have been permuted
+// using the given source of random numbers. The tests are traversed depth first so the same seed
+// always yields the same order.
+func shuffleTests(tests []Test, rnd *rand.Rand) []Test {
+	shuffled := make([]Test, len(tests))
+	for i, p := range rnd.Perm(len(tests)) {
+		shuffled[i] = tests[p]
+	}
+	for i, test := range shuffled {
+		if group, ok := test.(*TestGroup); ok {
+			shuffled[i] = &TestGroup{testNode: group.testNode, tests: shuffleTests(group.Tests(), rnd)}
+		}
+	}
+	return shuffled
+}
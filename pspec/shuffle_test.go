@@ -0,0 +1,23 @@
+package pspec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSeedIsPrintedOnceAfterSummary(t *testing.T) {
+	out, ok := runSpec(t, `
+Example('passes',
+  Given('1 + 1'),
+  Evaluates_to(2))
+`, Seed(0))
+	if !ok {
+		t.Fatalf("expected the run to pass, got:\n%s", out)
+	}
+	if n := strings.Count(out, `Randomized with seed 0`); n != 1 {
+		t.Fatalf("expected the seed to be printed once, got %d times in:\n%s", n, out)
+	}
+	if strings.Index(out, `Randomized with seed 0`) < strings.Index(out, `1 examples, 0 failures`) {
+		t.Errorf("expected the seed to be printed after the summary, got:\n%s", out)
+	}
+}
@@ -0,0 +1,206 @@
+package pspec
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/serialization"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-evaluator/pdsl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+type (
+	// SnapshotResult is the result of a Matches_snapshot expectation. The actual value is compared with
+	// a snapshot that is stored in a file in the __snapshots__ directory next to the spec file. Snapshots
+	// are created and replaced only when expectations are updated.
+	SnapshotResult struct {
+		location issue.Location
+		example  *Example
+		name     string
+		kind     string
+	}
+
+	// snapshotEntry is a named snapshot in a snapshot file
+	snapshotEntry struct {
+		name    string
+		content string
+	}
+)
+
+const (
+	snapshotEvaluation = `evaluation`
+	snapshotPN         = `pn`
+	snapshotDir        = `__snapshots__`
+)
+
+// snapshotLock serializes all reads and writes of snapshot files
+var snapshotLock sync.Mutex
+
+func (s *SnapshotResult) CreateTest(actual interface{}) Executable {
+	path, source, epp := pathContentAndEpp(actual)
+
+	return func(tc *TestContext, assertions Assertions) {
+		o := tc.ParserOptions()
+		if epp {
+			o = append(o, parser.EppMode)
+		}
+		expr, issues := parseAndValidate(path, tc.resolveLazyValue(source).String(), false, o...)
+		failOnError(assertions, issues)
+		key := snapshotKey(tc, s.name)
+		if s.kind == snapshotPN {
+			s.match(assertions, key, PrettyPN(stripBlock(expr).ToPN()))
+			return
+		}
+		tc.DoWithContext(func(c pdsl.EvaluationContext) {
+			result, evalIssues := evaluate(c, expr)
+			failOnError(assertions, evalIssues)
+			out := bytes.NewBufferString(``)
+			serialization.NewSerializer(c, px.EmptyMap).Convert(result, serialization.NewJsonStreamer(out))
+			s.match(assertions, key, out.String())
+		})
+	}
+}
+
+func (s *SnapshotResult) setExample(example *Example) {
+	s.example = example
+}
+
+// snapshotKey returns the key of the snapshot with the given name that is matched by the example of the
+// given context. The key consists of the descriptions of the example and its enclosing groups followed by
+// the name. When the example matches the same name more than once, as it does when it has several inputs,
+// the n-th match has " #n" appended to its key.
+func snapshotKey(tc *TestContext, name string) string {
+	if tc.snapshotCounts == nil {
+		tc.snapshotCounts = make(map[string]int)
+	}
+	tc.snapshotCounts[name]++
+	path := []string{name}
+	for c := tc; c != nil; c = c.parent {
+		path = append([]string{c.node.Description()}, path...)
+	}
+	key := strings.Join(path, `/`)
+	if n := tc.snapshotCounts[name]; n > 1 {
+		key = fmt.Sprintf(`%s #%d`, key, n)
+	}
+	return key
+}
+
+// match compares the given content with the snapshot stored under the given key. The snapshot is written
+// when the assertions are updating expectations and it doesn't exist or doesn't match.
+func (s *SnapshotResult) match(assertions Assertions, key, actual string) {
+	snapshotLock.Lock()
+	defer snapshotLock.Unlock()
+
+	file := snapshotFile(s.location.File())
+	entries, err := readSnapshots(file)
+	if err != nil {
+		assertions.Fail(err.Error())
+	}
+	u, ok := assertions.(goldenUpdater)
+	updating := ok && u.updating()
+	for _, e := range entries {
+		if e.name == key {
+			if e.content == actual {
+				return
+			}
+			if updating {
+				e.content = actual
+				s.write(assertions, file, entries)
+				u.snapshotWritten()
+				return
+			}
+			assertions.Fail(fmt.Sprintf("snapshot '%s' does not match\n%s", key, equalsMessage(e.content, actual)))
+			return
+		}
+	}
+	if !updating {
+		assertions.Fail(fmt.Sprintf(`snapshot '%s' does not exist in %s, run with updates enabled to create it`, key, file))
+		return
+	}
+	s.write(assertions, file, append(entries, &snapshotEntry{key, actual}))
+	u.snapshotWritten()
+}
+
+func (s *SnapshotResult) write(assertions Assertions, file string, entries []*snapshotEntry) {
+	if err := writeSnapshots(file, entries); err != nil {
+		assertions.Fail(err.Error())
+	}
+}
+
+// snapshotFile returns the path of the file that holds the snapshots of the given spec file
+func snapshotFile(specFile string) string {
+	return filepath.Join(filepath.Dir(specFile), snapshotDir, filepath.Base(specFile)+`.snap`)
+}
+
+// readSnapshots reads the entries of the given snapshot file. Each entry starts with a line that
+// contains its name enclosed in "-- " and " --" and continues until the next such line. A file that
+// doesn't exist has no entries.
+func readSnapshots(file string) ([]*snapshotEntry, error) {
+	entries := make([]*snapshotEntry, 0)
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var current *snapshotEntry
+	lines := make([]string, 0)
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, `-- `) && strings.HasSuffix(line, ` --`) && len(line) > 6 {
+			if current != nil {
+				current.content = strings.Join(lines, "\n")
+			}
+			current = &snapshotEntry{name: line[3 : len(line)-3]}
+			entries = append(entries, current)
+			lines = lines[:0]
+		} else if current != nil {
+			lines = append(lines, line)
+		}
+	}
+	if current != nil {
+		current.content = strings.Join(lines, "\n")
+	}
+	return entries, s.Err()
+}
+
+// writeSnapshots writes the given entries to the given snapshot file
+func writeSnapshots(file string, entries []*snapshotEntry) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	b := bytes.NewBufferString(``)
+	for _, e := range entries {
+		fmt.Fprintf(b, "-- %s --\n%s\n", e.name, e.content)
+	}
+	return ioutil.WriteFile(file, b.Bytes(), 0644)
+}
+
+func init() {
+	px.NewGoConstructor(`PSpec::Matches_snapshot`,
+		func(d px.Dispatch) {
+			d.Param(`String[1]`)
+			d.OptionalParam(`Enum['evaluation', 'pn']`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				kind := snapshotEvaluation
+				if len(args) > 1 {
+					kind = args[1].String()
+				}
+				return types.WrapRuntime(&SnapshotResult{location: c.StackTop(), name: args[0].String(), kind: kind})
+			})
+		})
+}
@@ -0,0 +1,55 @@
+package pspec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const snapshotSpec = `
+Examples('group',
+  Example('inputs',
+    Given(Source('1', '2')),
+    Matches_snapshot('value')))
+`
+
+func TestMissingSnapshotFails(t *testing.T) {
+	out, ok := runSpec(t, snapshotSpec)
+	if ok {
+		t.Fatalf("expected the run to fail, got:\n%s", out)
+	}
+	if !strings.Contains(out, `snapshot 'group/inputs/value' does not exist`) {
+		t.Errorf("expected a missing snapshot failure, got:\n%s", out)
+	}
+}
+
+func TestSnapshotsAreKeyedByExampleAndInput(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out, ok := runSpecIn(t, dir, snapshotSpec, Update())
+	if !ok {
+		t.Fatalf("expected the run to pass, got:\n%s", out)
+	}
+	if !strings.Contains(out, `Updated 2 expectations`) {
+		t.Errorf("expected the created snapshots to be counted, got:\n%s", out)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, snapshotDir, `test.pspec.snap`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "-- group/inputs/value --\n1\n-- group/inputs/value #2 --\n2\n"
+	if string(content) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, content)
+	}
+
+	out, ok = runSpecIn(t, dir, snapshotSpec)
+	if !ok {
+		t.Errorf("expected the created snapshots to match, got:\n%s", out)
+	}
+}
@@ -0,0 +1,158 @@
+package pspec
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// WhereClause is the rows of an Example_table declared using Where
+type WhereClause struct {
+	rows []px.List
+}
+
+// newExampleTable creates an Examples node that has one Example for each row. All elements of a row
+// except the last are used as arguments to the template format when creating the source of the example.
+// The last element is the expected result. It is either a SpecResult or the value that the source is
+// expected to evaluate to. An example is named after its formatted source unless the row contains lazy
+// values, in which case it's named after the row number. Each example has the location of its row when
+// the rows are given literally and the location of the table otherwise.
+func newExampleTable(c px.Context, description string, template px.Value, rows []px.List, args []px.Value) *Examples {
+	lets := make([]*LazyValueLet, 0)
+	var given *Given
+	options := make([]NodeOption, 0)
+	for _, arg := range args {
+		switch i := arg.(*types.RuntimeValue).Interface().(type) {
+		case *LazyValueLet:
+			lets = append(lets, i)
+		case *Given:
+			given = i
+		case NodeOption:
+			options = append(options, i)
+		}
+	}
+
+	location := c.StackTop()
+	rowLocations := tableRowLocations(c, len(rows))
+	children := make([]Node, len(rows))
+	for ri, row := range rows {
+		last := row.Len() - 1
+		inputs := make([]px.Value, last)
+		lazy := false
+		for i := 0; i < last; i++ {
+			inputs[i] = row.At(i)
+			if _, ok := inputs[i].(*types.RuntimeValue); ok {
+				lazy = true
+			}
+		}
+
+		var name string
+		if lazy {
+			name = fmt.Sprintf(`row %d`, ri+1)
+		} else {
+			name = types.PuppetSprintf(template.String(), inputs...)
+		}
+
+		var result Result
+		expected := row.At(last)
+		if rt, ok := expected.(*types.RuntimeValue); ok {
+			result, _ = rt.Interface().(Result)
+		}
+		if result == nil {
+			result = &EvaluationResult{expected: expected}
+		}
+
+		src := &Source{[]*source{{types.WrapRuntime(newFormatValue(template, inputs)), false}}}
+		rowLocation := location
+		if rowLocations != nil {
+			rowLocation = rowLocations[ri]
+		}
+		example := newExample(name, rowLocation, &Given{[]Input{src}}, []Result{result})
+		result.setExample(example)
+		children[ri] = example
+	}
+
+	ex := newExamples(description, location, given, children)
+	ex.addOptions(options)
+	ex.addLetDefs(lets)
+	return ex
+}
+
+// tableRowExpressions returns the expressions of the rows of the given Example_table call, or nil when the
+// rows are not given as a literal array or a Where clause
+func tableRowExpressions(call *parser.CallNamedFunctionExpression) []parser.Expression {
+	args := call.Arguments()
+	if len(args) < 3 {
+		return nil
+	}
+	switch rows := args[2].(type) {
+	case *parser.LiteralList:
+		return rows.Elements()
+	case *parser.CallNamedFunctionExpression:
+		return rows.Arguments()
+	}
+	return nil
+}
+
+// tableRowLocations returns the locations of the rows of the table that is being created, or nil when they
+// are unknown. The row expressions are consumed so that they are never used by another table.
+func tableRowLocations(c px.Context, count int) []issue.Location {
+	v, ok := c.Get(tableRows)
+	if !ok {
+		return nil
+	}
+	c.Set(tableRows, []parser.Expression(nil))
+	exprs, _ := v.([]parser.Expression)
+	if len(exprs) != count {
+		return nil
+	}
+	locations := make([]issue.Location, count)
+	for i, expr := range exprs {
+		locations[i] = expr
+	}
+	return locations
+}
+
+func init() {
+	px.NewGoConstructor(`PSpec::Where`,
+		func(d px.Dispatch) {
+			d.RepeatedParam(`Array[Any,1]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				rows := make([]px.List, len(args))
+				for i, arg := range args {
+					rows[i] = arg.(px.List)
+				}
+				return types.WrapRuntime(&WhereClause{rows})
+			})
+		})
+
+	px.NewGoConstructor2(`PSpec::Example_table`,
+		func(l px.LocalTypes) {
+			l.Type2(`Given`, types.NewGoRuntimeType(&Given{}))
+			l.Type2(`Let`, types.NewGoRuntimeType(&LazyValueLet{}))
+			l.Type2(`NodeOption`, types.NewGoRuntimeType((*NodeOption)(nil)))
+			l.Type2(`Where`, types.NewGoRuntimeType(&WhereClause{}))
+		},
+		func(d px.Dispatch) {
+			d.Param(`String`)
+			d.Param(`String`)
+			d.Param(`Variant[Array[Array[Any,1]],Where]`)
+			d.RepeatedParam(`Variant[Let,Given,NodeOption]`)
+			d.Function(func(c px.Context, args []px.Value) px.Value {
+				var rows []px.List
+				if rt, ok := args[2].(*types.RuntimeValue); ok {
+					rows = rt.Interface().(*WhereClause).rows
+				} else {
+					a := args[2].(px.List)
+					rows = make([]px.List, a.Len())
+					a.EachWithIndex(func(row px.Value, i int) {
+						rows[i] = row.(px.List)
+					})
+				}
+				return types.WrapRuntime(newExampleTable(c, args[0].String(), args[1], rows, args[3:]))
+			})
+		})
+}
@@ -0,0 +1,21 @@
+package pspec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineSelectsSingleTableRow(t *testing.T) {
+	out, ok := runSpec(t, `
+Example_table('doubles', '%s * 2', [
+  [1, 2],
+  [2, 4],
+  [3, 6]])
+`, Line(`test.pspec`, 4))
+	if !ok {
+		t.Fatalf("expected the run to pass, got:\n%s", out)
+	}
+	if !strings.Contains(out, `1 examples, 0 failures`) || !strings.Contains(out, `2 * 2`) {
+		t.Errorf("expected only the row on line 4 to run, got:\n%s", out)
+	}
+}
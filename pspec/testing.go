@@ -1,6 +1,9 @@
 package pspec
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/pcore/pcore"
 	"github.com/lyraproj/pcore/px"
@@ -32,8 +35,12 @@ type (
 		node           Node
 		accessedValues map[int64]px.Value
 		tearDowns      []Housekeeping
+		tearDownErrors []error
+		hookFailures   []string
 		scope          pdsl.Scope
+		settings       []*types.HashEntry
 		parserOptions  px.OrderedMap
+		snapshotCounts map[string]int
 	}
 
 	testNode struct {
@@ -48,9 +55,20 @@ type (
 	TestGroup struct {
 		testNode
 		tests []Test
+
+		// resolve, when set, creates the tests of the group the first time they are requested
+		resolve func() []Test
 	}
 )
 
+func newTestContext(parent *TestContext, node Node) *TestContext {
+	return &TestContext{
+		parent:         parent,
+		tearDowns:      make([]Housekeeping, 0),
+		accessedValues: make(map[int64]px.Value, 32),
+		node:           node}
+}
+
 func (tc *TestContext) Get(l LazyComputedValue) px.Value {
 	if v, ok := tc.accessedValues[l.Id()]; ok {
 		return v
@@ -61,6 +79,8 @@ func (tc *TestContext) Get(l LazyComputedValue) px.Value {
 	return v
 }
 
+// DoWithContext calls the given function with a new evaluation context that has a loader and a logger of
+// its own. Definitions made during the call are therefore never seen by other examples.
 func (tc *TestContext) DoWithContext(doer func(pdsl.EvaluationContext)) {
 	c := evaluator.NewContext(evaluator.NewEvaluator, px.NewParentedLoader(pcore.EnvironmentLoader()), px.NewArrayLogger())
 	px.DoWithContext(c, func(c px.Context) {
@@ -101,13 +121,39 @@ func (tc *TestContext) newLazyScope() *LazyScope {
 	return &LazyScope{*tc.Scope().(*evaluator.BasicScope), tc}
 }
 
+// Scope returns the scope of this context. A context that has no scope of its own will use a copy of the
+// scope of the closest enclosing context that has one, such as a scope that was set by a Before_all hook.
+// The copy ensures that variables assigned by one example are never seen by its siblings.
 func (tc *TestContext) Scope() pdsl.Scope {
 	if tc.scope == nil {
+		for p := tc.parent; p != nil; p = p.parent {
+			if p.scope != nil {
+				tc.scope = p.scope.Fork()
+				return tc.scope
+			}
+		}
 		tc.scope = evaluator.NewScope(false)
 	}
 	return tc.scope
 }
 
+// setSetting assigns the given value to the pcore setting with the given name and records the
+// assignment so that it can be reapplied by examples that run in an enclosed context.
+func (tc *TestContext) setSetting(key string, value px.Value) {
+	tc.settings = append(tc.settings, types.WrapHashEntry2(key, value))
+	pcore.Set(key, value)
+}
+
+// applySettings assigns the settings that were recorded by the enclosing contexts, outermost first
+func (tc *TestContext) applySettings() {
+	if tc.parent != nil {
+		tc.parent.applySettings()
+		for _, s := range tc.parent.settings {
+			pcore.Set(s.Key().String(), s.Value())
+		}
+	}
+}
+
 func (tc *TestContext) getLazyValue(key string) (LazyValue, bool) {
 	v, ok := tc.node.Get(key)
 	if ok {
@@ -119,6 +165,93 @@ func (tc *TestContext) getLazyValue(key string) (LazyValue, bool) {
 	return tc.parent.getLazyValue(key)
 }
 
+// skipReason returns the reason for skipping the node of this context or of the closest enclosing
+// context that is skipped
+func (tc *TestContext) skipReason() (string, bool) {
+	if reason, ok := tc.node.skipReason(); ok {
+		return reason, true
+	}
+	if tc.parent == nil {
+		return ``, false
+	}
+	return tc.parent.skipReason()
+}
+
+// pendingReason returns the reason why the node of this context, or of the closest enclosing context
+// that is pending, is pending
+func (tc *TestContext) pendingReason() (string, bool) {
+	if reason, ok := tc.node.pendingReason(); ok {
+		return reason, true
+	}
+	if tc.parent == nil {
+		return ``, false
+	}
+	return tc.parent.pendingReason()
+}
+
+// serial returns true if the node of this context, or of any enclosing context, was declared Serial
+func (tc *TestContext) serial() bool {
+	if tc.node.serial() {
+		return true
+	}
+	return tc.parent != nil && tc.parent.serial()
+}
+
+// exclusive returns true if the example of this context must not run concurrently with other examples. That
+// is the case when it is declared Serial, when it or its hooks change pcore settings, or when it shares the
+// settings or the scope that were established by a Before_all hook of an enclosing group.
+func (tc *TestContext) exclusive() bool {
+	if tc.serial() {
+		return true
+	}
+	for p := tc.parent; p != nil; p = p.parent {
+		if p.scope != nil || len(p.settings) > 0 {
+			return true
+		}
+	}
+	inputs := tc.node.collectInputs(tc, make([]Input, 0, 8))
+	for _, kind := range []hookKind{beforeEach, afterEach} {
+		for _, h := range tc.inheritedHooks(kind) {
+			inputs = append(inputs, h.inputs...)
+		}
+	}
+	for _, input := range inputs {
+		if _, ok := input.(*SettingsInput); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isolate acquires the hold of the settingsLock that the example of this context needs in order to run. An
+// example that is exclusive, or that doesn't run concurrently with other examples, is a writer and therefore
+// starts and ends with the default pcore settings so that nothing that it assigns is seen by other examples.
+// An example that runs concurrently never changes the settings and is isolated by evaluating in a context
+// of its own, see DoWithContext. Nil is returned when the example would have to wait for an example that
+// timed out.
+func (tc *TestContext) isolate(concurrent bool) *settingsHold {
+	if concurrent && !tc.exclusive() {
+		return settingsLock.acquire(false)
+	}
+	h := settingsLock.acquire(true)
+	if h != nil {
+		tc.applySettings()
+	}
+	return h
+}
+
+// timeout returns the timeout of the node of this context or of the closest enclosing context that has
+// a timeout. The given default is returned when no such context exists.
+func (tc *TestContext) timeout(dflt time.Duration) time.Duration {
+	if t := tc.node.timeout(); t > 0 {
+		return t
+	}
+	if tc.parent == nil {
+		return dflt
+	}
+	return tc.parent.timeout(dflt)
+}
+
 func (tc *TestContext) registerTearDown(td Housekeeping) {
 	tc.tearDowns = append(tc.tearDowns, td)
 }
@@ -167,28 +300,66 @@ func (v *TestExecutable) Executable() Executable {
 	return v.test
 }
 
+// Run runs the hooks and the test of this executable. The caller is responsible for resetting pcore and
+// for applying the settings of the enclosing contexts.
 func (v *TestExecutable) Run(ctx *TestContext, assertions Assertions) {
-	pcore.Reset()
+	// Tear downs and after hooks must run also when the assertions abort the test. The
+	// deferred functions run in reverse order so the innermost After_each runs first.
+	defer ctx.tearDown()
+	for _, h := range ctx.inheritedHooks(afterEach) {
+		defer h.run(ctx, assertions)
+	}
+	for _, h := range ctx.inheritedHooks(beforeEach) {
+		h.run(ctx, assertions)
+	}
 	v.test(ctx, assertions)
-	for i := len(ctx.tearDowns) - 1; i >= 0; i-- {
-		safeHousekeeping(ctx.tearDowns[i])
+}
+
+// tearDown runs the tear downs that have been registered with this context in reverse order
+func (tc *TestContext) tearDown() {
+	for i := len(tc.tearDowns) - 1; i >= 0; i-- {
+		if err := safeHousekeeping(tc.tearDowns[i]); err != nil {
+			tc.tearDownErrors = append(tc.tearDownErrors, err)
+		}
+	}
+}
+
+// TearDownErrors returns the errors that were raised by the tear downs that ran when the
+// test that used this context ended.
+func (tc *TestContext) TearDownErrors() []error {
+	return tc.tearDownErrors
+}
+
+// atLocation prefixes the given message with the file and line of the given location
+func atLocation(location issue.Location, message string) string {
+	if location == nil {
+		return message
 	}
+	return fmt.Sprintf(`%s:%d: %s`, location.File(), location.Line(), message)
 }
 
-func safeHousekeeping(h Housekeeping) {
+func safeHousekeeping(h Housekeeping) (err error) {
 	defer func() {
-		if err := recover(); err != nil {
-			if e, ok := err.(error); ok {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
 				pcore.Logger().Log(px.ERR, types.WrapString(e.Error()))
+				err = e
 			} else {
-				panic(err)
+				panic(r)
 			}
 		}
 	}()
 	h()
+	return
 }
 
+// Tests returns the tests of this group. The tests of a group that resolves its tests lazily are created
+// by the first call. Groups are always traversed by one goroutine at a time so no locking is needed.
 func (v *TestGroup) Tests() []Test {
+	if v.resolve != nil {
+		v.tests = v.resolve()
+		v.resolve = nil
+	}
 	return v.tests
 }
 
@@ -0,0 +1,34 @@
+package pspec_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/lyraproj/puppet-spec/pspec"
+)
+
+func TestAll(t *testing.T) {
+	pspec.RunPspecTests(t, `testdata`, nil)
+}
+
+func TestAllInParallel(t *testing.T) {
+	pspec.RunPspecTests(t, `testdata`, nil, pspec.Parallel(4))
+}
+
+// TestAllShuffled uses a fixed seed so that its outcome is reproducible. Another order is tested by setting
+// PSPEC_SEED.
+func TestAllShuffled(t *testing.T) {
+	seed := int64(1)
+	if s := os.Getenv(`PSPEC_SEED`); s != `` {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			t.Fatalf(`PSPEC_SEED: %s`, err.Error())
+		}
+		seed = n
+	}
+	pspec.RunPspecTests(t, `testdata`, nil, pspec.Seed(seed))
+	if t.Failed() {
+		t.Logf(`examples were shuffled with seed %d, use PSPEC_SEED=%d to reproduce`, seed, seed)
+	}
+}
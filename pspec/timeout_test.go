@@ -0,0 +1,69 @@
+package pspec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loopingTests returns a group with an example that doesn't return until the returned channel is closed
+// followed by an example that passes
+func loopingTests() ([]Test, chan struct{}) {
+	unblock := make(chan struct{})
+	loops := testExample(`loops`, `test.pspec`, 3)
+	loops.test = func(context *TestContext, assertions Assertions) { <-unblock }
+	passes := testExample(`passes`, `test.pspec`, 4)
+	passes.test = func(context *TestContext, assertions Assertions) {}
+	return []Test{testGroup(`timeouts`, `test.pspec`, 2, loops, passes)}, unblock
+}
+
+// awaitStalledExamples waits until no example that timed out holds the settingsLock
+func awaitStalledExamples(t *testing.T) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		settingsLock.cond.L.Lock()
+		stalled := settingsLock.stalledWriter || settingsLock.stalledReaders > 0
+		settingsLock.cond.L.Unlock()
+		if !stalled {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(`timed out example never released the settings lock`)
+}
+
+func TestLoopingExampleTimesOutWhileParallelRunContinues(t *testing.T) {
+	tests, unblock := loopingTests()
+	out := bytes.NewBufferString(``)
+	ok := NewRunner(out, true, DefaultTimeout(100*time.Millisecond), Parallel(2)).Run(tests)
+	close(unblock)
+	awaitStalledExamples(t)
+	if ok {
+		t.Fatalf("expected the run to fail, got:\n%s", out)
+	}
+	if !strings.Contains(out.String(), `test.pspec:3: example timed out after 100ms`) {
+		t.Errorf("expected a timeout failure at the location of the example, got:\n%s", out)
+	}
+	if !strings.Contains(out.String(), `--- PASS: timeouts/passes`) || !strings.Contains(out.String(), `2 examples, 1 failures`) {
+		t.Errorf("expected the other example to pass, got:\n%s", out)
+	}
+}
+
+func TestLoopingExampleTimesOutAndHoldsBackSerialRun(t *testing.T) {
+	tests, unblock := loopingTests()
+	out := bytes.NewBufferString(``)
+	ok := NewRunner(out, true, DefaultTimeout(100*time.Millisecond)).Run(tests)
+	close(unblock)
+	awaitStalledExamples(t)
+	if ok {
+		t.Fatalf("expected the run to fail, got:\n%s", out)
+	}
+	if !strings.Contains(out.String(), `test.pspec:3: example timed out after 100ms`) {
+		t.Errorf("expected a timeout failure at the location of the example, got:\n%s", out)
+	}
+	if !strings.Contains(out.String(), `2 examples, 1 failures, 1 not run`) ||
+		!strings.Contains(out.String(), `1 examples were not run because they would have to wait for an example that timed out`) {
+		t.Errorf("expected the other example to be held back, got:\n%s", out)
+	}
+}
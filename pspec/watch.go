@@ -0,0 +1,241 @@
+package pspec
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lyraproj/pcore/px"
+	"github.com/lyraproj/pcore/types"
+)
+
+// Watch runs the tests found in the given roots and then polls the file system at the given interval. When
+// a .pspec file changes, the tests that it declares are run again together with the tests that use shared
+// examples declared in it. When a path that is referenced by the Settings of an example changes, the tests
+// of the file that declares that example are run again. The progress of each run is written to the given
+// writer using a progress reporter, followed by the failures and the summary of the run. The summary also
+// names each passing, pending, and skipped example when verbose is true. Errors that occur when loading the
+// tests are written to the writer and all tests are run once the files change again. Watch returns only
+// when the test files cannot be found.
+func Watch(out io.Writer, verbose bool, interval time.Duration, roots []string, opts ...Option) error {
+	var stamps map[string]time.Time
+	var changed map[string]bool
+	references := make(map[string][]string)
+	for {
+		testFiles, err := FindTestFiles(roots...)
+		if err != nil {
+			return err
+		}
+		tests, err := loadWatched(testFiles)
+		if err != nil {
+			fmt.Fprintf(out, "%s\n%s\n", time.Now().Format(`15:04:05`), err.Error())
+		} else {
+			references = referencedPaths(tests)
+			if changed != nil {
+				tests = filterByFiles(tests, affectedFiles(changed, references))
+			}
+			if len(tests) > 0 {
+				runWatched(out, verbose, tests, opts)
+			}
+		}
+
+		stamps = modTimes(watchedFiles(testFiles, references))
+		for {
+			time.Sleep(interval)
+			if testFiles, err = FindTestFiles(roots...); err != nil {
+				return err
+			}
+			if changed = changedFiles(stamps, modTimes(watchedFiles(testFiles, references))); len(changed) > 0 {
+				break
+			}
+		}
+		if tests == nil {
+			// Nothing is known about what the changes affect when the previous load failed
+			changed = nil
+		}
+	}
+}
+
+// loadWatched loads the tests of the given files. A panic raised when evaluating the files is returned
+// as an error.
+func loadWatched(testFiles []string) (tests []Test, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			tests = nil
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf(`%v`, r)
+			}
+		}
+	}()
+	return LoadTests(testFiles, nil)
+}
+
+// runWatched runs the given tests with a progress reporter and writes the failures and the summary
+// once the run is done
+func runWatched(out io.Writer, verbose bool, tests []Test, opts []Option) {
+	details := bytes.NewBufferString(``)
+	fmt.Fprintf(out, "%s\n", time.Now().Format(`15:04:05`))
+	NewRunner(details, verbose, append(opts, WithReporter(NewProgressReporter(out)))...).Run(tests)
+	io.Copy(out, details)
+}
+
+// referencedPaths returns a map of the existing paths that are used in the Settings of the nodes of the
+// given tests and of the files that declare shared examples used by the tests. Each path is mapped to the
+// files that declare the nodes that use it.
+func referencedPaths(tests []Test) map[string][]string {
+	references := make(map[string][]string)
+	var collect func(tests []Test, parentContext *TestContext)
+	collect = func(tests []Test, parentContext *TestContext) {
+		for _, test := range tests {
+			n := test.Node()
+			ctx := newTestContext(parentContext, n)
+			inputs := n.collectInputs(newTestContext(nil, n), make([]Input, 0, 8))
+			for _, kind := range []hookKind{beforeEach, afterEach, beforeAll, afterAll} {
+				for _, h := range n.hooks(kind) {
+					inputs = append(inputs, h.inputs...)
+				}
+			}
+			for _, input := range inputs {
+				if s, ok := input.(*SettingsInput); ok {
+					for _, path := range settingsPaths(ctx, s.settings) {
+						if loc := n.Location(); loc != nil {
+							references[path] = append(references[path], loc.File())
+						}
+					}
+				}
+			}
+			if b, ok := n.(*BehavesLike); ok {
+				// The file that declares the shared examples is referenced by the file that uses them
+				if shared := b.sharedExamples(); shared != nil {
+					if sl, loc := shared.Location(), n.Location(); sl != nil && loc != nil && sl.File() != loc.File() {
+						references[sl.File()] = append(references[sl.File()], loc.File())
+					}
+				}
+			}
+			if g, ok := test.(*TestGroup); ok {
+				collect(g.Tests(), ctx)
+			}
+		}
+	}
+	collect(tests, nil)
+	return references
+}
+
+// settingsPaths returns the values of the given settings that are paths of existing files or directories.
+// Values that are obtained using Get are included when they refer to a Let with a literal value.
+func settingsPaths(ctx *TestContext, settings px.Value) []string {
+	paths := make([]string, 0)
+	hash, ok := settings.(*types.Hash)
+	if !ok {
+		return paths
+	}
+	hash.EachPair(func(_, v px.Value) {
+		if rt, ok := v.(*types.RuntimeValue); ok {
+			if lg, ok := rt.Interface().(*LazyValueGet); ok {
+				if lv, ok := ctx.getLazyValue(lg.valueName); ok {
+					if gv, ok := lv.(*GenericValue); ok {
+						v = gv.content
+					}
+				}
+			}
+		}
+		if s, ok := v.(px.StringValue); ok {
+			if _, err := os.Stat(s.String()); err == nil {
+				paths = append(paths, s.String())
+			}
+		}
+	})
+	return paths
+}
+
+// watchedFiles returns the given test files together with all files that are found in the referenced paths
+func watchedFiles(testFiles []string, references map[string][]string) []string {
+	files := append(make([]string, 0, len(testFiles)), testFiles...)
+	for path := range references {
+		filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// modTimes returns the modification times of the given files
+func modTimes(files []string) map[string]time.Time {
+	stamps := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = info.ModTime()
+		}
+	}
+	return stamps
+}
+
+// changedFiles returns the files that have been added, removed, or modified
+func changedFiles(before, after map[string]time.Time) map[string]bool {
+	changed := make(map[string]bool)
+	for file, t := range after {
+		if bt, ok := before[file]; !ok || !bt.Equal(t) {
+			changed[file] = true
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			changed[file] = true
+		}
+	}
+	return changed
+}
+
+// affectedFiles returns the test files that must run again when the given files have changed. That is
+// the changed files themselves and the files with nodes that reference a path that contains a changed or
+// affected file.
+func affectedFiles(changed map[string]bool, references map[string][]string) map[string]bool {
+	affected := make(map[string]bool, len(changed))
+	queue := make([]string, 0, len(changed))
+	for file := range changed {
+		queue = append(queue, file)
+	}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if affected[file] {
+			continue
+		}
+		affected[file] = true
+		for path, testFiles := range references {
+			if within(file, path) {
+				queue = append(queue, testFiles...)
+			}
+		}
+	}
+	return affected
+}
+
+// within returns true if the given file is the given path or is contained in the directory appointed by it
+func within(file, path string) bool {
+	rel, err := filepath.Rel(path, file)
+	return err == nil && rel != `..` && !strings.HasPrefix(rel, `..`+string(filepath.Separator))
+}
+
+// filterByFiles returns the tests that are declared in the given files
+func filterByFiles(tests []Test, files map[string]bool) []Test {
+	return selectTests(tests, nil, func(ancestors []Test, test Test) bool {
+		if loc := test.Node().Location(); loc != nil {
+			for file := range files {
+				if sameFile(loc.File(), file) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
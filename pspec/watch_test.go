@@ -0,0 +1,40 @@
+package pspec
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAffectedFilesFollowsSharedExamples(t *testing.T) {
+	references := map[string][]string{
+		`shared/base.pspec`:    {`shared/derived.pspec`},
+		`shared/derived.pspec`: {`user.pspec`},
+		`fixtures`:             {`settings.pspec`},
+	}
+	affected := affectedFiles(map[string]bool{`shared/base.pspec`: true}, references)
+	expected := map[string]bool{`shared/base.pspec`: true, `shared/derived.pspec`: true, `user.pspec`: true}
+	if !reflect.DeepEqual(expected, affected) {
+		t.Errorf("expected %v, got %v", expected, affected)
+	}
+}
+
+func TestLoadWatchedReturnsPanicsAsErrors(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `pspec`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `test.pspec`)
+	spec := "Shared_examples('twice', Example('a', Given('1'), Evaluates_to(1)))\n" +
+		"Shared_examples('twice', Example('b', Given('1'), Evaluates_to(1)))\n"
+	if err = ioutil.WriteFile(file, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests, err := loadWatched([]string{file})
+	if err == nil || tests != nil {
+		t.Errorf("expected an error and no tests, got %v and %v", err, tests)
+	}
+}